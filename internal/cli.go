@@ -0,0 +1,374 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
+)
+
+// Exit codes of commands. Like diff(1), commands exit with exitFailure if a config
+// is invalid or configs differ, so they can be used in pre-commit hooks and scripts.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// A command of the provider binary, run by RunCommand.
+type command struct {
+	usage       string
+	description string
+	run         func(cli *commandLine, flags *flag.FlagSet, args []string) int
+}
+
+var commands = map[string]command{
+	"render": {
+		usage:       "render [options] CONTENT [SNIPPET...]",
+		description: "Render content and snippets like ignition_config and print the Ignition config.",
+		run:         runRender,
+	},
+	"validate": {
+		usage:       "validate [options] CONTENT [SNIPPET...]",
+		description: "Render content and snippets like ignition_config and print diagnostics only.",
+		run:         runValidate,
+	},
+	"inspect": {
+		usage:       "inspect IGNITION",
+		description: "Print the attributes of ignition_config_inspect for an Ignition config as JSON.",
+		run:         runInspect,
+	},
+	"diff": {
+		usage:       "diff OLD NEW",
+		description: "Print the summary of ignition_config_diff for two Ignition configs.",
+		run:         runDiff,
+	},
+}
+
+var commandNames = []string{"render", "validate", "inspect", "diff"}
+
+// Streams of a command. Files named - are read from stdin.
+type commandLine struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run a command of the provider binary, e.g. to render a config outside of Terraform with
+// exactly the Butane and Ignition versions of the provider. Returns the exit code.
+func RunCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cli := &commandLine{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		cli.usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		cli.usage()
+		return exitUsage
+	}
+	return cmd.run(cli, cli.flagSet(args[0], cmd), args[1:])
+}
+
+func (cli *commandLine) usage() {
+	fmt.Fprintf(cli.stderr, "Usage: %s COMMAND [options] [FILE...]\n\n", os.Args[0])
+	fmt.Fprintf(cli.stderr, "Without a command the binary serves the Terraform provider plugin.\n\nCommands:\n")
+	for _, name := range commandNames {
+		fmt.Fprintf(cli.stderr, "  %-40s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintf(cli.stderr, "\nRun %s COMMAND -h for the options of a command.\n", os.Args[0])
+}
+
+// Returns a flag set which prints the usage of a command.
+func (cli *commandLine) flagSet(name string, cmd command) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cli.stderr, "Usage: %s %s\n\n%s\n", os.Args[0], cmd.usage, cmd.description)
+		var hasFlags bool
+		flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(cli.stderr, "\nOptions:\n")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+// Parse the flags of a command and check the number of files. max is -1 for any number of files.
+// Returns false with the exit code of the command if it must not run, exitOK if -h printed the usage.
+func (cli *commandLine) parse(flags *flag.FlagSet, args []string, min, max int) ([]string, int, bool) {
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil, exitOK, false
+	} else if err != nil {
+		return nil, exitUsage, false
+	}
+	files := flags.Args()
+	if len(files) < min || (max >= 0 && len(files) > max) {
+		flags.Usage()
+		return nil, exitUsage, false
+	}
+	return files, exitOK, true
+}
+
+func (cli *commandLine) readFile(name string) (string, error) {
+	var contents []byte
+	var err error
+	if name == "-" {
+		contents, err = io.ReadAll(cli.stdin)
+	} else {
+		contents, err = os.ReadFile(name)
+	}
+	return string(contents), err
+}
+
+func (cli *commandLine) fail(err error) int {
+	fmt.Fprintf(cli.stderr, "Error: %v\n", err)
+	return exitFailure
+}
+
+// Print diagnostics like Terraform. Details name the inputs content and snippet 0, 1, ...
+// in the order of the files.
+func (cli *commandLine) printDiagnostics(diags render.Diagnostics) {
+	for _, d := range diags {
+		severity := "Warning"
+		if d.Severity == render.SeverityError {
+			severity = "Error"
+		}
+		fmt.Fprintf(cli.stderr, "%s: %s\n", severity, d.Summary)
+		if d.Detail != "" {
+			fmt.Fprintf(cli.stderr, "  %s\n", d.Detail)
+		}
+	}
+}
+
+// Options of render and validate, the arguments of ignition_config.
+type renderFlags struct {
+	options      render.Options
+	versions     string
+	conflicts    string
+	checks       string
+	secrets      string
+	fileFormats  keyValueFlag
+	diskSizes    keyValueFlag
+	allowlist    listFlag
+	rules        listFlag
+	allowedHosts listFlag
+}
+
+func addRenderFlags(flags *flag.FlagSet) *renderFlags {
+	defaults := render.DefaultOptions()
+	f := &renderFlags{}
+	flags.BoolVar(&f.options.Strict, "strict", false, "treat Butane translation warnings as errors")
+	flags.BoolVar(&f.options.PrettyPrint, "pretty-print", false, "indent the rendered config")
+	flags.StringVar(&f.options.FilesDir, "files-dir", "", "directory of local files embedded by content and snippets")
+	flags.StringVar(&f.versions, "version-strategy", string(defaults.VersionStrategy), "Ignition spec version of the rendered config: content, newest or latest")
+	flags.StringVar(&f.conflicts, "conflict-policy", string(defaults.ConflictPolicy), "how entries defined by more than one input are handled: last_wins, warn or error")
+	flags.BoolVar(&f.options.LintUnits, "lint-units", defaults.LintUnits, "report problems of units and dropins as warnings")
+	flags.BoolVar(&f.options.ValidatePaths, "validate-paths", defaults.ValidatePaths, "check nodes against the writable locations of the variant")
+	flags.BoolVar(&f.options.ValidateAccounts, "validate-accounts", defaults.ValidateAccounts, "check users and groups referenced by the config")
+	flags.BoolVar(&f.options.ValidateFiles, "validate-files", defaults.ValidateFiles, "validate the syntax of inline files")
+	flags.Var(&f.fileFormats, "file-format", "format of files to validate as `PATTERN=FORMAT`, e.g. /etc/app/*.conf=toml (repeatable)")
+	flags.BoolVar(&f.options.ValidateStorage, "validate-storage", defaults.ValidateStorage, "check the partition layout and device references")
+	flags.StringVar(&f.checks, "check-severity", string(defaults.CheckSeverity), "severity of path, account and storage problems: warning or error")
+	flags.Var(&f.diskSizes, "disk-size", "size of a disk as `DEVICE=MIB`, e.g. /dev/vdb=10240 (repeatable)")
+	flags.StringVar(&f.secrets, "scan-secrets", string(defaults.ScanSecrets), "how secrets in file and unit contents are reported: off, warn or error")
+	flags.Var(&f.allowlist, "secret-allowlist", "file path, unit name or `PATTERN` of them which may contain secrets (repeatable)")
+	flags.Var(&f.rules, "rule", "built-in rule `NAME`, or NAME=EXPRESSION for a custom CEL rule (repeatable)")
+	flags.Var(&f.allowedHosts, "allowed-host", "`HOST` remote sources may be fetched from, used by the allowed_source_hosts rule (repeatable)")
+	return f
+}
+
+// Render options with content and snippets read from files.
+func (f *renderFlags) expand(cli *commandLine, files []string) (render.Options, error) {
+	options := f.options
+	options.VersionStrategy = render.VersionStrategy(f.versions)
+	options.ConflictPolicy = render.ConflictPolicy(f.conflicts)
+	options.CheckSeverity = render.Severity(f.checks)
+	options.ScanSecrets = render.SecretScan(f.secrets)
+	options.SecretAllowlist = f.allowlist
+	options.FileFormats = f.fileFormats.values
+	options.DiskSizes = map[string]int{}
+	for device, size := range f.diskSizes.values {
+		mib, err := strconv.Atoi(size)
+		if err != nil {
+			return options, fmt.Errorf("disk-size: size of %s must be a number of MiB, got %q", device, size)
+		}
+		options.DiskSizes[device] = mib
+	}
+	for _, rule := range f.rules {
+		name, expression, _ := strings.Cut(rule, "=")
+		options.Rules = append(options.Rules, render.Rule{Name: name, Expression: expression, AllowedHosts: f.allowedHosts})
+	}
+
+	var err error
+	if options.Content, err = cli.readFile(files[0]); err != nil {
+		return options, err
+	}
+	for _, file := range files[1:] {
+		snippet, err := cli.readFile(file)
+		if err != nil {
+			return options, err
+		}
+		options.Snippets = append(options.Snippets, snippet)
+	}
+	return options, nil
+}
+
+// Render content and snippets, printing diagnostics. ok is false if rendering failed.
+func (f *renderFlags) render(cli *commandLine, files []string) (result render.Result, ok bool) {
+	options, err := f.expand(cli, files)
+	if err != nil {
+		cli.fail(err)
+		return result, false
+	}
+	result, err = render.Render(context.Background(), options)
+	var renderErr *render.Error
+	switch {
+	case errors.As(err, &renderErr):
+		cli.printDiagnostics(renderErr.Diagnostics)
+		return result, false
+	case err != nil:
+		cli.fail(err)
+		return result, false
+	}
+	cli.printDiagnostics(result.Warnings)
+	return result, true
+}
+
+func runRender(cli *commandLine, flags *flag.FlagSet, args []string) int {
+	renderFlags := addRenderFlags(flags)
+	files, code, ok := cli.parse(flags, args, 1, -1)
+	if !ok {
+		return code
+	}
+	result, ok := renderFlags.render(cli, files)
+	if !ok {
+		return exitFailure
+	}
+	fmt.Fprintf(cli.stdout, "%s\n", result.Rendered)
+	return exitOK
+}
+
+func runValidate(cli *commandLine, flags *flag.FlagSet, args []string) int {
+	renderFlags := addRenderFlags(flags)
+	failOnWarnings := flags.Bool("fail-on-warnings", false, "exit with 1 if there are warnings")
+	files, code, ok := cli.parse(flags, args, 1, -1)
+	if !ok {
+		return code
+	}
+	result, ok := renderFlags.render(cli, files)
+	if !ok || (*failOnWarnings && len(result.Warnings) > 0) {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func runInspect(cli *commandLine, flags *flag.FlagSet, args []string) int {
+	files, code, ok := cli.parse(flags, args, 1, 1)
+	if !ok {
+		return code
+	}
+	content, err := cli.readFile(files[0])
+	if err != nil {
+		return cli.fail(err)
+	}
+	config, err := configutil.ParseRendered([]byte(content))
+	if err != nil {
+		return cli.fail(fmt.Errorf("%s: %v", files[0], err))
+	}
+	version, _, _ := ignition_util.GetConfigVersion([]byte(content))
+	attributes, err := inspectConfig(config, version.String())
+	if err != nil {
+		return cli.fail(err)
+	}
+	output, err := json.MarshalIndent(attributes, "", "  ")
+	if err != nil {
+		return cli.fail(err)
+	}
+	fmt.Fprintf(cli.stdout, "%s\n", output)
+	return exitOK
+}
+
+func runDiff(cli *commandLine, flags *flag.FlagSet, args []string) int {
+	files, code, ok := cli.parse(flags, args, 2, 2)
+	if !ok {
+		return code
+	}
+	var contents [2]string
+	var configs [2]types_v3_4.Config
+	for i, file := range files {
+		var err error
+		if contents[i], err = cli.readFile(file); err != nil {
+			return cli.fail(err)
+		}
+		if configs[i], err = configutil.ParseRendered([]byte(contents[i])); err != nil {
+			return cli.fail(fmt.Errorf("%s: %v", file, err))
+		}
+	}
+	oldVersion, _, _ := ignition_util.GetConfigVersion([]byte(contents[0]))
+	newVersion, _, _ := ignition_util.GetConfigVersion([]byte(contents[1]))
+	diff := diffConfigs(configs[0], configs[1])
+	diff.oldVersion = oldVersion.String()
+	diff.newVersion = newVersion.String()
+	if !diff.changed && diff.oldVersion == diff.newVersion {
+		return exitOK
+	}
+	fmt.Fprint(cli.stdout, diff.summary())
+	return exitFailure
+}
+
+// A flag which may be given more than once.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// A flag of KEY=VALUE pairs which may be given more than once.
+type keyValueFlag struct {
+	values map[string]string
+}
+
+func (f *keyValueFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var pairs []string
+	for _, key := range configutil.SortedKeys(f.values) {
+		pairs = append(pairs, key+"="+f.values[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *keyValueFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	if f.values == nil {
+		f.values = map[string]string{}
+	}
+	f.values[key] = val
+	return nil
+}
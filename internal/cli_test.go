@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runTestCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := RunCommand(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunCommand(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"content.bu": "variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/motd\n      contents:\n        inline: hello\n",
+		"unit.bu":    "variant: fcos\nversion: 1.5.0\nsystemd:\n  units:\n    - name: app.service\n      enabled: true\n      contents: \"[Service]\\nExecStart=/bin/app\\n\"\n",
+		"broken.bu":  "variant: fcos\nversion: 9.9.9\n",
+	})
+	content, unit, broken := filepath.Join(dir, "content.bu"), filepath.Join(dir, "unit.bu"), filepath.Join(dir, "broken.bu")
+
+	code, rendered, stderr := runTestCommand(t, "", "render", content, unit)
+	if code != exitOK || !strings.Contains(rendered, `"path":"/etc/motd"`) || !strings.Contains(rendered, `"name":"app.service"`) {
+		t.Fatalf("expected a rendered config, got %d %q %q", code, rendered, stderr)
+	}
+	if !strings.Contains(stderr, "Warning: systemd unit problem\n  unit app.service (snippet 0): enabled, but [Install]") {
+		t.Errorf("expected unit problems as warnings, got %q", stderr)
+	}
+	if strings.Contains(stderr, "Error:") {
+		t.Errorf("expected no errors, got %q", stderr)
+	}
+
+	for _, test := range []struct {
+		args     []string
+		code     int
+		expected string
+	}{
+		{[]string{"validate", content, unit}, exitOK, "Warning: systemd unit problem"},
+		{[]string{"validate", "-fail-on-warnings", content, unit}, exitFailure, "Warning: systemd unit problem"},
+		{[]string{"validate", "-lint-units=false", "-fail-on-warnings", content, unit}, exitOK, ""},
+		{[]string{"validate", content, broken, broken}, exitFailure, "Error: snippet parse error: "},
+		{[]string{"validate", "-rule", "no_world_writable", "-rule", "small=size(config.storage.files) < 1", content}, exitFailure, "rule small"},
+		{[]string{"render", "-disk-size", "/dev/vdb=big", content}, exitFailure, "Error: disk-size: size of /dev/vdb must be a number of MiB"},
+		{[]string{"render"}, exitUsage, "Usage: "},
+		{[]string{"render", "-unknown", content}, exitUsage, "flag provided but not defined"},
+		{[]string{"render", filepath.Join(dir, "missing.bu")}, exitFailure, "no such file or directory"},
+		{[]string{"deploy"}, exitUsage, `unknown command "deploy"`},
+		{[]string{"help"}, exitOK, "Commands:"},
+		{[]string{"-h"}, exitOK, "Commands:"},
+		{[]string{"render", "-h"}, exitOK, "Usage: "},
+		{[]string{"diff", "-help"}, exitOK, "Usage: "},
+	} {
+		code, _, stderr := runTestCommand(t, "", test.args...)
+		if code != test.code || !strings.Contains(stderr, test.expected) {
+			t.Errorf("%v: expected %d %q, got %d %q", test.args, test.code, test.expected, code, stderr)
+		}
+		if test.expected == "" && stderr != "" {
+			t.Errorf("%v: expected no output, got %q", test.args, stderr)
+		}
+	}
+
+	// inspect and diff rendered configs, reading one of them from stdin
+	_, renderedContent, _ := runTestCommand(t, "", "render", content)
+	dir = writeTestFiles(t, map[string]string{"old.ign": renderedContent, "new.ign": rendered})
+	code, inspected, _ := runTestCommand(t, rendered, "inspect", "-")
+	if code != exitOK || !strings.Contains(inspected, `"contents": "hello"`) {
+		t.Errorf("expected the inspected config, got %d %q", code, inspected)
+	}
+	code, inspected, _ = runTestCommand(t, `{"ignition": {"version": "3.2.0"}}`, "inspect", "-")
+	if code != exitOK || !strings.Contains(inspected, `"version": "3.2.0"`) {
+		t.Errorf("expected the version of the config, got %d %q", code, inspected)
+	}
+	code, summary, _ := runTestCommand(t, "", "diff", filepath.Join(dir, "old.ign"), filepath.Join(dir, "new.ign"))
+	if code != exitFailure || summary != "+ unit app.service\n" {
+		t.Errorf("expected the added unit, got %d %q", code, summary)
+	}
+	code, summary, _ = runTestCommand(t, rendered, "diff", "-", filepath.Join(dir, "new.ign"))
+	if code != exitOK || summary != "" {
+		t.Errorf("expected no changes, got %d %q", code, summary)
+	}
+	if code, _, stderr := runTestCommand(t, "{", "inspect", "-"); code != exitFailure || !strings.HasPrefix(stderr, "Error: -: ") {
+		t.Errorf("expected a parse error, got %d %q", code, stderr)
+	}
+}
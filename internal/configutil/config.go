@@ -0,0 +1,216 @@
+// Package configutil contains helpers for Ignition configs which are shared by the
+// render pipeline and the data sources inspecting rendered configs.
+package configutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	ignition_v3_4 "github.com/coreos/ignition/v2/config/v3_4"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Parse a rendered Ignition config of any supported version and translate it
+// to the newest supported spec, so that callers only have to deal with one set of types.
+func ParseRendered(rawConfig []byte) (types_v3_4.Config, error) {
+	version, _, err := ignition_util.GetConfigVersion(rawConfig)
+	if err != nil {
+		return types_v3_4.Config{}, err
+	}
+	if _, err := LibraryForVersion(version.String()); err != nil {
+		return types_v3_4.Config{}, fmt.Errorf("version %s: %v", version.String(), err)
+	}
+	config, report, err := ignition_v3_4.ParseCompatibleVersion(rawConfig)
+	if err != nil {
+		return types_v3_4.Config{}, fmt.Errorf("%v: %v", err, report.String())
+	}
+	return config, nil
+}
+
+// Translate a transpiled Ignition configuration object of any supported version
+// to the newest supported spec.
+func Normalize(ignitionConfig interface{}) (types_v3_4.Config, error) {
+	rawConfig, err := json.Marshal(ignitionConfig)
+	if err != nil {
+		return types_v3_4.Config{}, err
+	}
+	return ParseRendered(rawConfig)
+}
+
+// Returns true if the resource is embedded in the config as a data URL.
+func IsInlineResource(resource types_v3_4.Resource) bool {
+	return resource.Source != nil && strings.HasPrefix(*resource.Source, "data:")
+}
+
+// Returns the source URL of a resource which Ignition has to fetch at boot time,
+// or an empty string for inline and empty resources.
+func RemoteSource(resource types_v3_4.Resource) string {
+	if resource.Source == nil || IsInlineResource(resource) {
+		return ""
+	}
+	return *resource.Source
+}
+
+// A resource of a config with a description of where it is used.
+type NamedResource struct {
+	Name     string
+	Resource types_v3_4.Resource
+}
+
+// Collect all resources of a config, in config order.
+func Resources(config types_v3_4.Config) []NamedResource {
+	var resources []NamedResource
+	add := func(resource types_v3_4.Resource, format string, args ...interface{}) {
+		if resource.Source != nil {
+			resources = append(resources, NamedResource{fmt.Sprintf(format, args...), resource})
+		}
+	}
+	add(config.Ignition.Config.Replace, "ignition config replace")
+	for i, resource := range config.Ignition.Config.Merge {
+		add(resource, "ignition config merge %d", i)
+	}
+	for i, resource := range config.Ignition.Security.TLS.CertificateAuthorities {
+		add(resource, "ignition certificate authority %d", i)
+	}
+	for _, file := range config.Storage.Files {
+		add(file.Contents, "file %s", file.Path)
+		for i, resource := range file.Append {
+			add(resource, "file %s append %d", file.Path, i)
+		}
+	}
+	for _, luks := range config.Storage.Luks {
+		add(luks.KeyFile, "luks %s key file", luks.Name)
+	}
+	return resources
+}
+
+// Collect all URLs Ignition would fetch at boot time, in config order.
+func RemoteSources(config types_v3_4.Config) []string {
+	var sources []string
+	for _, resource := range Resources(config) {
+		if source := RemoteSource(resource.Resource); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// Decode the contents of an inline resource, undoing compression.
+// Remote resources are never fetched; ok is false for them.
+func DecodeResource(resource types_v3_4.Resource) (contents []byte, ok bool, err error) {
+	if resource.Source == nil {
+		return []byte{}, true, nil
+	}
+	if !IsInlineResource(resource) {
+		return nil, false, nil
+	}
+	url, err := dataurl.DecodeString(*resource.Source)
+	if err != nil {
+		return nil, false, err
+	}
+	contents = url.Data
+	if resource.Compression != nil && *resource.Compression != "" {
+		switch *resource.Compression {
+		case "gzip":
+			reader, err := gzip.NewReader(bytes.NewReader(contents))
+			if err != nil {
+				return nil, false, err
+			}
+			defer reader.Close()
+			if contents, err = io.ReadAll(reader); err != nil {
+				return nil, false, err
+			}
+		default:
+			return nil, false, fmt.Errorf("unsupported compression %q", *resource.Compression)
+		}
+	}
+	return contents, true, nil
+}
+
+func FormatMode(mode *int) string {
+	if mode == nil {
+		return ""
+	}
+	return fmt.Sprintf("%04o", *mode)
+}
+
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func IntValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func BoolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// Re-encode all inline resources as uncompressed data URLs, so that configs can
+// be compared regardless of how their contents were encoded.
+func Canonicalize(config types_v3_4.Config) types_v3_4.Config {
+	canonicalize := func(resource *types_v3_4.Resource) {
+		if resource.Compression != nil && *resource.Compression == "" {
+			resource.Compression = nil
+		}
+		if resource.Verification.Hash != nil {
+			return
+		}
+		if contents, ok, err := DecodeResource(*resource); err == nil && ok && resource.Source != nil {
+			source := dataurl.EncodeBytes(contents)
+			resource.Source = &source
+			resource.Compression = nil
+		}
+	}
+	canonicalize(&config.Ignition.Config.Replace)
+	config.Ignition.Config.Merge = append([]types_v3_4.Resource{}, config.Ignition.Config.Merge...)
+	for i := range config.Ignition.Config.Merge {
+		canonicalize(&config.Ignition.Config.Merge[i])
+	}
+	config.Ignition.Security.TLS.CertificateAuthorities = append([]types_v3_4.Resource{}, config.Ignition.Security.TLS.CertificateAuthorities...)
+	for i := range config.Ignition.Security.TLS.CertificateAuthorities {
+		canonicalize(&config.Ignition.Security.TLS.CertificateAuthorities[i])
+	}
+	config.Storage.Files = append([]types_v3_4.File{}, config.Storage.Files...)
+	for i := range config.Storage.Files {
+		canonicalize(&config.Storage.Files[i].Contents)
+		config.Storage.Files[i].Append = append([]types_v3_4.Resource{}, config.Storage.Files[i].Append...)
+		for j := range config.Storage.Files[i].Append {
+			canonicalize(&config.Storage.Files[i].Append[j])
+		}
+	}
+	config.Storage.Luks = append([]types_v3_4.Luks{}, config.Storage.Luks...)
+	for i := range config.Storage.Luks {
+		canonicalize(&config.Storage.Luks[i].KeyFile)
+	}
+	return config
+}
+
+// Sorted union of the keys of the given maps.
+func SortedKeys[V any](maps ...map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package configutil
+
+import (
+	"errors"
+
+	ignition_v3_0 "github.com/coreos/ignition/v2/config/v3_0"
+	types_v3_0 "github.com/coreos/ignition/v2/config/v3_0/types"
+	ignition_v3_1 "github.com/coreos/ignition/v2/config/v3_1"
+	types_v3_1 "github.com/coreos/ignition/v2/config/v3_1/types"
+	ignition_v3_2 "github.com/coreos/ignition/v2/config/v3_2"
+	types_v3_2 "github.com/coreos/ignition/v2/config/v3_2/types"
+	ignition_v3_3 "github.com/coreos/ignition/v2/config/v3_3"
+	types_v3_3 "github.com/coreos/ignition/v2/config/v3_3/types"
+	ignition_v3_4 "github.com/coreos/ignition/v2/config/v3_4"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/coreos/vcontext/report"
+)
+
+func parseV30(rawConfig []byte) (interface{}, report.Report, error) {
+	return ignition_v3_0.ParseCompatibleVersion(rawConfig)
+}
+
+func mergeV30(parent interface{}, child interface{}) interface{} {
+	return ignition_v3_0.Merge(parent.(types_v3_0.Config), child.(types_v3_0.Config))
+}
+
+func parseV31(rawConfig []byte) (interface{}, report.Report, error) {
+	return ignition_v3_1.ParseCompatibleVersion(rawConfig)
+}
+
+func mergeV31(parent interface{}, child interface{}) interface{} {
+	return ignition_v3_1.Merge(parent.(types_v3_1.Config), child.(types_v3_1.Config))
+}
+
+func parseV32(rawConfig []byte) (interface{}, report.Report, error) {
+	return ignition_v3_2.ParseCompatibleVersion(rawConfig)
+}
+
+func mergeV32(parent interface{}, child interface{}) interface{} {
+	return ignition_v3_2.Merge(parent.(types_v3_2.Config), child.(types_v3_2.Config))
+}
+
+func parseV33(rawConfig []byte) (interface{}, report.Report, error) {
+	return ignition_v3_3.ParseCompatibleVersion(rawConfig)
+}
+
+func mergeV33(parent interface{}, child interface{}) interface{} {
+	return ignition_v3_3.Merge(parent.(types_v3_3.Config), child.(types_v3_3.Config))
+}
+
+func parseV34(rawConfig []byte) (interface{}, report.Report, error) {
+	return ignition_v3_4.ParseCompatibleVersion(rawConfig)
+}
+
+func mergeV34(parent, child interface{}) interface{} {
+	return ignition_v3_4.Merge(parent.(types_v3_4.Config), child.(types_v3_4.Config))
+}
+
+// Parse and merge functions of an Ignition spec version. Configs are the types of the
+// version, e.g. types_v3_4.Config, which Parse returns as interface{}.
+type Library struct {
+	Parse func(rawConfig []byte) (interface{}, report.Report, error)
+	Merge func(parent interface{}, child interface{}) interface{}
+}
+
+var versionToLibrary = map[string]Library{
+	"3.0.0": {
+		Parse: parseV30,
+		Merge: mergeV30,
+	},
+	"3.1.0": {
+		Parse: parseV31,
+		Merge: mergeV31,
+	},
+	"3.2.0": {
+		Parse: parseV32,
+		Merge: mergeV32,
+	},
+	"3.3.0": {
+		Parse: parseV33,
+		Merge: mergeV33,
+	},
+	"3.4.0": {
+		Parse: parseV34,
+		Merge: mergeV34,
+	},
+}
+
+// Returns the library of a supported Ignition spec version.
+func LibraryForVersion(version string) (Library, error) {
+	ignition, ok := versionToLibrary[version]
+	if !ok {
+		return Library{}, errors.New("incompatible version")
+	}
+	return ignition, nil
+}
@@ -0,0 +1,319 @@
+// Package crypt creates and recognizes crypt(3) password hashes as used in /etc/shadow.
+package crypt
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blowfish"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+const (
+	Yescrypt         = "yescrypt"
+	SHA512           = "sha512crypt"
+	Bcrypt           = "bcrypt"
+	cryptAlphabet    = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	bcryptAlphabet   = "./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	sha512Rounds     = 5000
+	sha512MinRounds  = 1000
+	sha512MaxRounds  = 999999999
+	sha512SaltLength = 16
+	bcryptCost       = 10
+	bcryptMinCost    = 4
+	bcryptMaxCost    = 31
+	bcryptSaltBytes  = 16
+	yescryptSetting  = "$y$j9T$"
+)
+
+var bcryptEncoding = base64.NewEncoding(bcryptAlphabet).WithPadding(base64.NoPadding)
+
+// Hash a password with the crypt(3) algorithm of the given name. The salt is encoded
+// in the alphabet of the algorithm, cost is the number of rounds of sha512crypt or the
+// cost of bcrypt, 0 selects the default.
+func Password(algorithm string, password, salt string, cost int) (string, error) {
+	switch algorithm {
+	case Yescrypt:
+		if cost != 0 {
+			return "", fmt.Errorf("cost is not supported for %s", algorithm)
+		}
+		saltBytes, err := decodeYescrypt64(salt)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s salt %q: %v", algorithm, salt, err)
+		}
+		key, err := yescryptKey([]byte(password), saltBytes, yescryptN, yescryptR)
+		if err != nil {
+			return "", err
+		}
+		return yescryptSetting + salt + "$" + encodeYescrypt64(key), nil
+	case SHA512:
+		if cost == 0 {
+			cost = sha512Rounds
+		}
+		if cost < sha512MinRounds || cost > sha512MaxRounds {
+			return "", fmt.Errorf("cost of %s must be between %d and %d rounds", algorithm, sha512MinRounds, sha512MaxRounds)
+		}
+		if len(salt) > sha512SaltLength || strings.ContainsAny(salt, "$:\n") {
+			return "", fmt.Errorf("invalid %s salt %q: at most %d characters without $ and :", algorithm, salt, sha512SaltLength)
+		}
+		return sha512Crypt([]byte(password), []byte(salt), cost), nil
+	case Bcrypt:
+		if cost == 0 {
+			cost = bcryptCost
+		}
+		if cost < bcryptMinCost || cost > bcryptMaxCost {
+			return "", fmt.Errorf("cost of %s must be between %d and %d", algorithm, bcryptMinCost, bcryptMaxCost)
+		}
+		if len(password) > 72 {
+			return "", fmt.Errorf("%s passwords are limited to 72 bytes", algorithm)
+		}
+		saltBytes, err := bcryptEncoding.DecodeString(salt)
+		if err != nil || len(salt) != 22 {
+			return "", fmt.Errorf("invalid %s salt %q: must be 22 characters of %s", algorithm, salt, bcryptAlphabet)
+		}
+		return bcryptHash([]byte(password), saltBytes, cost)
+	}
+	return "", fmt.Errorf("unknown algorithm %q", algorithm)
+}
+
+// Encode bytes as a salt of the given algorithm.
+func EncodeSalt(algorithm string, raw []byte) string {
+	switch algorithm {
+	case Yescrypt:
+		return encodeYescrypt64(raw[:16])
+	case Bcrypt:
+		return bcryptEncoding.EncodeToString(raw[:bcryptSaltBytes])
+	}
+	var salt strings.Builder
+	for _, b := range raw[:sha512SaltLength] {
+		salt.WriteByte(cryptAlphabet[b&0x3f])
+	}
+	return salt.String()
+}
+
+// Base64 of crypt hashes: little endian groups of 24 bits.
+func encodeYescrypt64(src []byte) string {
+	var dst strings.Builder
+	for i := 0; i < len(src); {
+		value, bits := uint32(0), 0
+		for bits < 24 && i < len(src) {
+			value |= uint32(src[i]) << bits
+			bits += 8
+			i++
+		}
+		for ; bits > 0; bits -= 6 {
+			dst.WriteByte(cryptAlphabet[value&0x3f])
+			value >>= 6
+		}
+	}
+	return dst.String()
+}
+
+func decodeYescrypt64(src string) ([]byte, error) {
+	var dst []byte
+	for len(src) > 0 {
+		value, bits := uint32(0), 0
+		for bits < 24 && len(src) > 0 {
+			c := strings.IndexByte(cryptAlphabet, src[0])
+			if c < 0 {
+				return nil, fmt.Errorf("invalid character %q", src[0])
+			}
+			value |= uint32(c) << bits
+			bits += 6
+			src = src[1:]
+		}
+		if bits < 12 {
+			return nil, fmt.Errorf("truncated encoding")
+		}
+		for ; bits >= 8; bits -= 8 {
+			dst = append(dst, byte(value))
+			value >>= 8
+		}
+		if value != 0 {
+			return nil, fmt.Errorf("non-zero trailing bits")
+		}
+	}
+	return dst, nil
+}
+
+// sha512crypt as specified by Ulrich Drepper.
+func sha512Crypt(password, salt []byte, rounds int) string {
+	b := sha512.New()
+	b.Write(password)
+	b.Write(salt)
+	b.Write(password)
+	digestB := b.Sum(nil)
+
+	a := sha512.New()
+	a.Write(password)
+	a.Write(salt)
+	n := len(password)
+	for ; n > 64; n -= 64 {
+		a.Write(digestB)
+	}
+	a.Write(digestB[:n])
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			a.Write(digestB)
+		} else {
+			a.Write(password)
+		}
+	}
+	digestA := a.Sum(nil)
+
+	dp := sha512.New()
+	for range password {
+		dp.Write(password)
+	}
+	p := repeatBytes(dp.Sum(nil), len(password))
+
+	ds := sha512.New()
+	for i := 0; i < 16+int(digestA[0]); i++ {
+		ds.Write(salt)
+	}
+	s := repeatBytes(ds.Sum(nil), len(salt))
+
+	c := digestA
+	for i := 0; i < rounds; i++ {
+		h := sha512.New()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+
+	var result strings.Builder
+	result.WriteString("$6$")
+	if rounds != sha512Rounds {
+		fmt.Fprintf(&result, "rounds=%d$", rounds)
+	}
+	result.Write(salt)
+	result.WriteByte('$')
+	encode := func(b2, b1, b0 byte, n int) {
+		w := uint32(b2)<<16 | uint32(b1)<<8 | uint32(b0)
+		for ; n > 0; n-- {
+			result.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for i := 0; i < 21; i++ {
+		x, y, z := c[i], c[i+21], c[i+42]
+		switch i % 3 {
+		case 0:
+			encode(x, y, z, 4)
+		case 1:
+			encode(y, z, x, 4)
+		case 2:
+			encode(z, x, y, 4)
+		}
+	}
+	encode(0, 0, c[63], 2)
+	return result.String()
+}
+
+func repeatBytes(digest []byte, length int) []byte {
+	result := make([]byte, 0, length)
+	for len(result) < length {
+		n := length - len(result)
+		if n > len(digest) {
+			n = len(digest)
+		}
+		result = append(result, digest[:n]...)
+	}
+	return result
+}
+
+// bcrypt in its $2b$ variant, as golang.org/x/crypto/bcrypt does not accept a salt.
+func bcryptHash(password, salt []byte, cost int) (string, error) {
+	key := append(password[:len(password):len(password)], 0)
+	cipher, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+	for i := uint64(0); i < 1<<uint(cost); i++ {
+		blowfish.ExpandKey(key, cipher)
+		blowfish.ExpandKey(salt, cipher)
+	}
+	data := []byte("OrpheanBeholderScryDoubt")
+	for i := 0; i < 24; i += 8 {
+		for j := 0; j < 64; j++ {
+			cipher.Encrypt(data[i:i+8], data[i:i+8])
+		}
+	}
+	// only 23 of the 24 bytes are encoded, for compatibility with the original implementation
+	return fmt.Sprintf("$2b$%02d$%s%s", cost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(data[:23])), nil
+}
+
+// Patterns of supported crypt hashes.
+var cryptHashPatterns = map[string]*regexp.Regexp{
+	Yescrypt:        regexp.MustCompile(`^\$y\$[./0-9A-Za-z]+\$[./0-9A-Za-z]*\$[./0-9A-Za-z]{43}$`),
+	"gost-yescrypt": regexp.MustCompile(`^\$gy\$[./0-9A-Za-z]+\$[./0-9A-Za-z]*\$[./0-9A-Za-z]{43}$`),
+	"scrypt":        regexp.MustCompile(`^\$7\$[./0-9A-Za-z]{11,}\$[./0-9A-Za-z]{43}$`),
+	SHA512:          regexp.MustCompile(`^\$6\$(rounds=[0-9]+\$)?[^$:\n]{0,16}\$[./0-9A-Za-z]{86}$`),
+	"sha256crypt":   regexp.MustCompile(`^\$5\$(rounds=[0-9]+\$)?[^$:\n]{0,16}\$[./0-9A-Za-z]{43}$`),
+	Bcrypt:          regexp.MustCompile(`^\$2[aby]\$[0-9]{2}\$[./A-Za-z0-9]{53}$`),
+}
+
+// Patterns of crypt hashes which are supported, but easily cracked.
+var weakCryptHashPatterns = map[string]*regexp.Regexp{
+	"md5crypt": regexp.MustCompile(`^\$1\$[^$:\n]{0,8}\$[./0-9A-Za-z]{22}$`),
+	"DES":      regexp.MustCompile(`^[./0-9A-Za-z]{13}$`),
+}
+
+// Describe the problem of the password_hash of a passwd user or group, or return an
+// empty string if it is fine. weak is true if the hash is valid, but easily cracked.
+func HashProblem(hash string) (problem string, weak bool) {
+	algorithm, weak, ok := HashAlgorithm(hash)
+	switch {
+	case !ok:
+		return "password_hash is not a yescrypt, sha512crypt, sha256crypt or bcrypt hash, create one with the ignition_password_hash data source", false
+	case weak:
+		return fmt.Sprintf("password_hash uses %s, which is easily cracked, use yescrypt instead", algorithm), true
+	}
+	return "", false
+}
+
+// Determine the algorithm of a crypt hash. weak is true for outdated algorithms,
+// ok is false if the hash is not a crypt hash. Hashes of locked accounts start with ! or *;
+// accounts which are locked without a hash, e.g. "!" or "*", have no algorithm.
+func HashAlgorithm(hash string) (algorithm string, weak, ok bool) {
+	unlocked := strings.TrimLeft(hash, "!*")
+	if unlocked == "" && hash != "" {
+		return "", false, true
+	}
+	hash = unlocked
+	for _, name := range configutil.SortedKeys(cryptHashPatterns) {
+		if cryptHashPatterns[name].MatchString(hash) {
+			if name == Bcrypt {
+				if cost, _ := strconv.Atoi(hash[4:6]); cost < bcryptMinCost || cost > bcryptMaxCost {
+					return "", false, false
+				}
+			}
+			return name, false, true
+		}
+	}
+	for _, name := range configutil.SortedKeys(weakCryptHashPatterns) {
+		if weakCryptHashPatterns[name].MatchString(hash) {
+			return name, true, true
+		}
+	}
+	return "", false, false
+}
@@ -0,0 +1,65 @@
+package crypt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		password  string
+		salt      string
+		cost      int
+		hash      string
+	}{
+		// generated with crypt(3) of libxcrypt
+		{Yescrypt, "password", "F5Jx5fExrKuPp53xLKQ..1", 0, "$y$j9T$F5Jx5fExrKuPp53xLKQ..1$tnSYvahCwPBHKZUspmcxMfb0.WiB9W.zEaKlOBL35rC"},
+		{Yescrypt, "", "F5Jx5fExrKuPp53xLKQ..1", 0, "$y$j9T$F5Jx5fExrKuPp53xLKQ..1$5P1uc1zvKhieqEtKttbwCQrTPXpY1cK9wEnTDKAqLD8"},
+		{Yescrypt, strings.Repeat("a", 100), "abcdefghijklmnopqrstu.", 0, "$y$j9T$abcdefghijklmnopqrstu.$wRNM6ysdQhdMw/YcqvFXXrJCmBhac.FkNa16NW5BX14"},
+		{SHA512, "password", "saltsalt", 0, "$6$saltsalt$qFmFH.bQmmtXzyBY0s9v7Oicd2z4XSIecDzlB5KiA2/jctKu9YterLp8wwnSq.qc.eoxqOmSuNp2xS0ktL3nh/"},
+		{SHA512, "password", "saltsaltsaltsalt", 10000, "$6$rounds=10000$saltsaltsaltsalt$ven1Z5uj7RDawucOM1A67FJkxPT8ty925GjP2I3C1I1otSzw05mv4Y24JfAguQfbNUK9F68UUwzofnEXKC9xP/"},
+		{SHA512, strings.Repeat("x", 100), "ab", 0, "$6$ab$Dl9IhjoQKEbFzT0mHUojnmKfO/iaqCONKYMJae6A6BamPi8p5W8x7BIsEQTkgeX2qGN0TnWVc1L7jgOlphKFW."},
+		{Bcrypt, "password", "abcdefghijklmnopqrstuu", 5, "$2b$05$abcdefghijklmnopqrstuuWG29KuyeAicPCJODk1zjyGvyQUU2awu"},
+		{Bcrypt, "", "abcdefghijklmnopqrstuu", 4, "$2b$04$abcdefghijklmnopqrstuubyCG3zY1GIXMyxfivm.ClDiInHzxjiq"},
+	}
+	for _, test := range tests {
+		hash, err := Password(test.algorithm, test.password, test.salt, test.cost)
+		if err != nil {
+			t.Errorf("%s %q: %v", test.algorithm, test.password, err)
+			continue
+		}
+		if hash != test.hash {
+			t.Errorf("%s %q: expected %s, got %s", test.algorithm, test.password, test.hash, hash)
+		}
+		if algorithm, weak, ok := HashAlgorithm(hash); !ok || weak || algorithm != test.algorithm {
+			t.Errorf("%s: detected as %q, weak %v, ok %v", hash, algorithm, weak, ok)
+		}
+	}
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		hash      string
+		algorithm string
+		weak      bool
+		ok        bool
+	}{
+		{"!$6$saltsalt$qFmFH.bQmmtXzyBY0s9v7Oicd2z4XSIecDzlB5KiA2/jctKu9YterLp8wwnSq.qc.eoxqOmSuNp2xS0ktL3nh/", SHA512, false, true},
+		{"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZF4gbDo5C", "sha256crypt", false, true},
+		{"$1$saltsalt$qjXMvbEw8oaL.CzflDugX/", "md5crypt", true, true},
+		{"$2b$99$abcdefghijklmnopqrstuuWG29KuyeAicPCJODk1zjyGvyQUU2awu", "", false, false},
+		{"hunter2", "", false, false},
+		{"!", "", false, true},
+		{"!!", "", false, true},
+		{"*", "", false, true},
+		{"", "", false, false},
+		{"$6$saltsalt$tooshort", "", false, false},
+	}
+	for _, test := range tests {
+		algorithm, weak, ok := HashAlgorithm(test.hash)
+		if algorithm != test.algorithm || weak != test.weak || ok != test.ok {
+			t.Errorf("%s: expected %q, weak %v, ok %v, got %q, %v, %v", test.hash, test.algorithm, test.weak, test.ok, algorithm, weak, ok)
+		}
+	}
+}
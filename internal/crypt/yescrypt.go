@@ -0,0 +1,294 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// yescrypt as used by libxcrypt for $y$ hashes, following the reference implementation
+// by Alexander Peslyak. Only the parameters of crypt hashes are supported: p = 1, t = 0,
+// no ROM and the default flavor with pwxform.
+const (
+	yescryptRW      = 0x002
+	yescryptPrehash = 0x10000000
+
+	// default flavor: YESCRYPT_RW | ROUNDS_6 | GATHER_4 | SIMPLE_2 | SBOX_12K
+	yescryptDefaults = 0x0b6
+
+	pwxSimple = 2
+	pwxGather = 4
+	pwxRounds = 6
+	sWidth    = 8
+
+	pwxBytes = pwxGather * pwxSimple * 8
+	pwxWords = pwxBytes / 8
+	sBytes   = 3 * (1 << sWidth) * pwxSimple * 8
+	sMask    = ((1 << sWidth) - 1) * pwxSimple * 8
+)
+
+// Parameters of libxcrypt's default $y$j9T$ setting.
+const (
+	yescryptN = 4096
+	yescryptR = 32
+)
+
+// State of pwxform: the three S-boxes as 32 bit words and the write position in S2.
+type pwxformContext struct {
+	s0, s1, s2 []uint32
+	w          int
+}
+
+// Compute a yescrypt hash of 32 bytes with the default flavor.
+func yescryptKey(password, salt []byte, n uint64, r int) ([]byte, error) {
+	if n < 4 || n&(n-1) != 0 || r < 1 {
+		return nil, errors.New("yescrypt: invalid parameters")
+	}
+	flags := uint32(yescryptDefaults)
+	if n >= 0x100 && n*uint64(r) >= 0x20000 {
+		prehashed := yescryptBody(password, salt, flags|yescryptPrehash, n>>6, r)
+		password = prehashed
+	}
+	return yescryptBody(password, salt, flags, n, r), nil
+}
+
+func yescryptBody(password, salt []byte, flags uint32, n uint64, r int) []byte {
+	key := "yescrypt"
+	if flags&yescryptPrehash != 0 {
+		key = "yescrypt-prehash"
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(password)
+	password = mac.Sum(nil)
+
+	b := pbkdf2.Key(password, salt, 1, 128*r, sha256.New)
+	passwd := append([]byte(nil), b[:32]...)
+	yescryptSMix(b, r, n, flags, passwd)
+
+	out := pbkdf2.Key(passwd, b, 1, 32, sha256.New)
+	if flags&yescryptPrehash == 0 {
+		// ClientKey and StoredKey of SCRAM
+		mac := hmac.New(sha256.New, out)
+		mac.Write([]byte("Client Key"))
+		stored := sha256.Sum256(mac.Sum(nil))
+		out = stored[:]
+	}
+	return out
+}
+
+// SMix with p = 1 and t = 0. passwd is updated in place.
+func yescryptSMix(b []byte, r int, n uint64, flags uint32, passwd []byte) {
+	s := 32 * r
+	nloopAll := (n + 2) / 3
+	nloopRW := nloopAll
+	nloopAll = (nloopAll + 1) &^ 1
+	nloopRW = (nloopRW + 1) &^ 1
+
+	v := make([]uint32, uint64(s)*n)
+	x := make([]uint32, s)
+	y := make([]uint32, s)
+
+	// initialize the S-boxes with classic scrypt SMix1 on the first block
+	sbox := make([]uint32, sBytes/4)
+	xs := make([]uint32, 32)
+	ys := make([]uint32, 32)
+	yescryptSMix1(b[:128], 1, sBytes/128, 0, sbox, xs, ys, nil)
+	ctx := &pwxformContext{
+		s2: sbox[:sBytes/3/4],
+		s1: sbox[sBytes/3/4 : 2*sBytes/3/4],
+		s0: sbox[2*sBytes/3/4:],
+	}
+	mac := hmac.New(sha256.New, b[128*r-64:128*r])
+	mac.Write(passwd)
+	copy(passwd, mac.Sum(nil))
+
+	yescryptSMix1(b, r, n, flags, v, x, y, ctx)
+	yescryptSMix2(b, r, n, nloopRW, flags, v, x, y, ctx)
+	yescryptSMix2(b, r, n, nloopAll-nloopRW, flags&^yescryptRW, v, x, y, ctx)
+}
+
+// Load a block from bytes into words in the SIMD friendly order of the reference implementation.
+func yescryptLoad(x []uint32, b []byte, r int) {
+	for k := 0; k < 2*r; k++ {
+		for i := 0; i < 16; i++ {
+			x[k*16+i] = binary.LittleEndian.Uint32(b[(k*16+(i*5%16))*4:])
+		}
+	}
+}
+
+func yescryptStore(b []byte, x []uint32, r int) {
+	for k := 0; k < 2*r; k++ {
+		for i := 0; i < 16; i++ {
+			binary.LittleEndian.PutUint32(b[(k*16+(i*5%16))*4:], x[k*16+i])
+		}
+	}
+}
+
+func yescryptSMix1(b []byte, r int, n uint64, flags uint32, v, x, y []uint32, ctx *pwxformContext) {
+	s := 32 * r
+	yescryptLoad(x, b, r)
+	for i := uint64(0); i < n; i++ {
+		copy(v[i*uint64(s):], x)
+		if flags&yescryptRW != 0 && i > 1 {
+			j := yescryptWrap(yescryptIntegerify(x, r), i)
+			blockXor(x, v[j*uint64(s):(j+1)*uint64(s)])
+		}
+		if ctx != nil {
+			blockMixPwxform(x, ctx, r)
+		} else {
+			blockMixSalsa8(x, y, r)
+		}
+	}
+	yescryptStore(b, x, r)
+}
+
+func yescryptSMix2(b []byte, r int, n, nloop uint64, flags uint32, v, x, y []uint32, ctx *pwxformContext) {
+	if nloop == 0 {
+		return
+	}
+	s := uint64(32 * r)
+	yescryptLoad(x, b, r)
+	for i := uint64(0); i < nloop; i++ {
+		j := yescryptIntegerify(x, r) & (n - 1)
+		blockXor(x, v[j*s:(j+1)*s])
+		if flags&yescryptRW != 0 {
+			copy(v[j*s:], x)
+		}
+		if ctx != nil {
+			blockMixPwxform(x, ctx, r)
+		} else {
+			blockMixSalsa8(x, y, r)
+		}
+	}
+	yescryptStore(b, x, r)
+}
+
+// Words 0 and 13 of the last block hold words 0 and 1 in the original order.
+func yescryptIntegerify(x []uint32, r int) uint64 {
+	last := x[(2*r-1)*16:]
+	return uint64(last[13])<<32 | uint64(last[0])
+}
+
+func yescryptWrap(x, i uint64) uint64 {
+	n := uint64(1) << (63 - bits.LeadingZeros64(i))
+	return (x & (n - 1)) + (i - n)
+}
+
+func blockXor(dst, src []uint32) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+func blockMixSalsa8(b, y []uint32, r int) {
+	var x [16]uint32
+	copy(x[:], b[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		blockXor(x[:], b[i*16:(i+1)*16])
+		salsa20(x[:], 8)
+		copy(y[i*16:], x[:])
+	}
+	for i := 0; i < r; i++ {
+		copy(b[i*16:], y[(i*2)*16:(i*2+1)*16])
+	}
+	for i := 0; i < r; i++ {
+		copy(b[(i+r)*16:], y[(i*2+1)*16:(i*2+2)*16])
+	}
+}
+
+func blockMixPwxform(b []uint32, ctx *pwxformContext, r int) {
+	var x [pwxWords * 2]uint32
+	r1 := 128 * r / pwxBytes
+	copy(x[:], b[(r1-1)*pwxWords*2:])
+	for i := 0; i < r1; i++ {
+		if r1 > 1 {
+			blockXor(x[:], b[i*pwxWords*2:(i+1)*pwxWords*2])
+		}
+		pwxform(x[:], ctx)
+		copy(b[i*pwxWords*2:], x[:])
+	}
+	i := (r1 - 1) * pwxBytes / 64
+	salsa20(b[i*16:(i+1)*16], 2)
+	for i++; i < 2*r; i++ {
+		blockXor(b[i*16:(i+1)*16], b[(i-1)*16:i*16])
+		salsa20(b[i*16:(i+1)*16], 2)
+	}
+}
+
+func pwxform(x []uint32, ctx *pwxformContext) {
+	s0, s1, s2 := ctx.s0, ctx.s1, ctx.s2
+	w := ctx.w
+	for i := 0; i < pwxRounds; i++ {
+		for j := 0; j < pwxGather; j++ {
+			xl := x[j*pwxSimple*2]
+			xh := x[j*pwxSimple*2+1]
+			p0 := int(xl&sMask) / 4
+			p1 := int(xh&sMask) / 4
+			for k := 0; k < pwxSimple; k++ {
+				lo, hi := x[(j*pwxSimple+k)*2], x[(j*pwxSimple+k)*2+1]
+				v := uint64(hi) * uint64(lo)
+				v += uint64(s0[p0+2*k+1])<<32 | uint64(s0[p0+2*k])
+				v ^= uint64(s1[p1+2*k+1])<<32 | uint64(s1[p1+2*k])
+				x[(j*pwxSimple+k)*2] = uint32(v)
+				x[(j*pwxSimple+k)*2+1] = uint32(v >> 32)
+				if i != 0 && i != pwxRounds-1 {
+					s2[2*w] = uint32(v)
+					s2[2*w+1] = uint32(v >> 32)
+					w++
+				}
+			}
+		}
+	}
+	ctx.s0, ctx.s1, ctx.s2 = s2, s0, s1
+	ctx.w = w & ((1<<sWidth)*pwxSimple - 1)
+}
+
+// Salsa20 core on a block in SIMD friendly order, adding the result to the input.
+func salsa20(b []uint32, rounds int) {
+	var x [16]uint32
+	for i := 0; i < 16; i++ {
+		x[i*5%16] = b[i]
+	}
+	for i := 0; i < rounds; i += 2 {
+		x[4] ^= bits.RotateLeft32(x[0]+x[12], 7)
+		x[8] ^= bits.RotateLeft32(x[4]+x[0], 9)
+		x[12] ^= bits.RotateLeft32(x[8]+x[4], 13)
+		x[0] ^= bits.RotateLeft32(x[12]+x[8], 18)
+		x[9] ^= bits.RotateLeft32(x[5]+x[1], 7)
+		x[13] ^= bits.RotateLeft32(x[9]+x[5], 9)
+		x[1] ^= bits.RotateLeft32(x[13]+x[9], 13)
+		x[5] ^= bits.RotateLeft32(x[1]+x[13], 18)
+		x[14] ^= bits.RotateLeft32(x[10]+x[6], 7)
+		x[2] ^= bits.RotateLeft32(x[14]+x[10], 9)
+		x[6] ^= bits.RotateLeft32(x[2]+x[14], 13)
+		x[10] ^= bits.RotateLeft32(x[6]+x[2], 18)
+		x[3] ^= bits.RotateLeft32(x[15]+x[11], 7)
+		x[7] ^= bits.RotateLeft32(x[3]+x[15], 9)
+		x[11] ^= bits.RotateLeft32(x[7]+x[3], 13)
+		x[15] ^= bits.RotateLeft32(x[11]+x[7], 18)
+
+		x[1] ^= bits.RotateLeft32(x[0]+x[3], 7)
+		x[2] ^= bits.RotateLeft32(x[1]+x[0], 9)
+		x[3] ^= bits.RotateLeft32(x[2]+x[1], 13)
+		x[0] ^= bits.RotateLeft32(x[3]+x[2], 18)
+		x[6] ^= bits.RotateLeft32(x[5]+x[4], 7)
+		x[7] ^= bits.RotateLeft32(x[6]+x[5], 9)
+		x[4] ^= bits.RotateLeft32(x[7]+x[6], 13)
+		x[5] ^= bits.RotateLeft32(x[4]+x[7], 18)
+		x[11] ^= bits.RotateLeft32(x[10]+x[9], 7)
+		x[8] ^= bits.RotateLeft32(x[11]+x[10], 9)
+		x[9] ^= bits.RotateLeft32(x[8]+x[11], 13)
+		x[10] ^= bits.RotateLeft32(x[9]+x[8], 18)
+		x[12] ^= bits.RotateLeft32(x[15]+x[14], 7)
+		x[13] ^= bits.RotateLeft32(x[12]+x[15], 9)
+		x[14] ^= bits.RotateLeft32(x[13]+x[12], 13)
+		x[15] ^= bits.RotateLeft32(x[14]+x[13], 18)
+	}
+	for i := 0; i < 16; i++ {
+		b[i] += x[i*5%16]
+	}
+}
@@ -0,0 +1,539 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"golang.org/x/crypto/ssh"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
+)
+
+func datasourceAssert() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceAssertRead,
+
+		Schema: map[string]*schema.Schema{
+			"content": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "rendered ignition configuration to check",
+			},
+			"severity": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      string(render.SeverityError),
+				ValidateFunc: validation.StringInSlice([]string{string(render.SeverityError), string(render.SeverityWarning)}, false),
+				Description:  "severity of failed assertions",
+			},
+			"file": assertionSchema(map[string]*schema.Schema{
+				"path": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"exists": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"contents_match": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					ValidateFunc: validation.StringIsValidRegExp,
+					Description:  "regular expression the decoded contents, including appended contents, must match",
+				},
+				"mode": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					ValidateFunc: validation.StringMatch(regexp.MustCompile(`^0?[0-7]{3,4}$`), "must be an octal mode, e.g. 0644"),
+				},
+				"owner": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "name or id of the user owning the file, root if the config sets none",
+				},
+				"group": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "name or id of the group owning the file, root if the config sets none",
+				},
+			}),
+			"unit": assertionSchema(map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"exists": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"enabled": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"masked": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"contains": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "strings the contents of the unit or one of its dropins must contain",
+				},
+			}),
+			"user": assertionSchema(map[string]*schema.Schema{
+				"name": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"exists": {
+					Type:     schema.TypeBool,
+					Optional: true,
+					Default:  true,
+				},
+				"has_ssh_keys": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Description: "whether the user has any SSH authorized keys",
+				},
+				"ssh_key": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "public key or SHA256 fingerprint of an SSH key the user must have",
+				},
+				"groups": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "groups the user must be a member of, including the primary group",
+				},
+			}),
+			"kernel_argument": assertionSchema(map[string]*schema.Schema{
+				"value": {
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"present": {
+					Type:        schema.TypeBool,
+					Optional:    true,
+					Default:     true,
+					Description: "whether the argument should exist, or should not exist",
+				},
+			}),
+			"assertions": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "number of checked assertions",
+			},
+		},
+	}
+}
+
+func assertionSchema(elem map[string]*schema.Schema) *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem:     &schema.Resource{Schema: elem},
+	}
+}
+
+// Expected state of a file. Unset expectations are nil or empty.
+type fileAssertion struct {
+	path          string
+	exists        bool
+	contentsMatch *regexp.Regexp
+	mode          *int
+	owner         string
+	group         string
+}
+
+// Expected state of a unit. enabled and masked are nil if unset.
+type unitAssertion struct {
+	name     string
+	exists   bool
+	enabled  *bool
+	masked   *bool
+	contains []string
+}
+
+// Expected state of a user. hasSSHKeys is nil if unset.
+type userAssertion struct {
+	name       string
+	exists     bool
+	hasSSHKeys *bool
+	sshKey     string
+	groups     []string
+}
+
+type kernelArgumentAssertion struct {
+	value   string
+	present bool
+}
+
+type assertions struct {
+	files           []fileAssertion
+	units           []unitAssertion
+	users           []userAssertion
+	kernelArguments []kernelArgumentAssertion
+}
+
+func (a assertions) count() int {
+	return len(a.files) + len(a.units) + len(a.users) + len(a.kernelArguments)
+}
+
+func datasourceAssertRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	content := d.Get("content").(string)
+	config, err := configutil.ParseRendered([]byte(content))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("content parse error: %v", err))
+	}
+	expected, err := expandAssertions(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	severity := diag.Error
+	if d.Get("severity").(string) == string(render.SeverityWarning) {
+		severity = diag.Warning
+	}
+	for _, failure := range checkAssertions(config, expected) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: severity,
+			Summary:  "assertion failed",
+			Detail:   failure,
+		})
+	}
+	if diags.HasError() {
+		return diags
+	}
+	if err := d.Set("assertions", expected.count()); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	d.SetId(hashcode(content))
+	return diags
+}
+
+func expandAssertions(d *schema.ResourceData) (assertions, error) {
+	var expected assertions
+	for i, raw := range d.Get("file").([]interface{}) {
+		attributes := raw.(map[string]interface{})
+		file := fileAssertion{
+			path:   attributes["path"].(string),
+			exists: attributes["exists"].(bool),
+			owner:  attributes["owner"].(string),
+			group:  attributes["group"].(string),
+		}
+		if pattern := attributes["contents_match"].(string); pattern != "" {
+			file.contentsMatch = regexp.MustCompile(pattern)
+		}
+		if mode := attributes["mode"].(string); mode != "" {
+			parsed, err := strconv.ParseInt(mode, 8, 32)
+			if err != nil {
+				return expected, fmt.Errorf("file.%d.mode: %v", i, err)
+			}
+			value := int(parsed)
+			file.mode = &value
+		}
+		expected.files = append(expected.files, file)
+	}
+	for i, raw := range d.Get("unit").([]interface{}) {
+		attributes := raw.(map[string]interface{})
+		expected.units = append(expected.units, unitAssertion{
+			name:     attributes["name"].(string),
+			exists:   attributes["exists"].(bool),
+			enabled:  optionalBool(d, fmt.Sprintf("unit.%d.enabled", i)),
+			masked:   optionalBool(d, fmt.Sprintf("unit.%d.masked", i)),
+			contains: expandStringList(attributes["contains"]),
+		})
+	}
+	for i, raw := range d.Get("user").([]interface{}) {
+		attributes := raw.(map[string]interface{})
+		user := userAssertion{
+			name:       attributes["name"].(string),
+			exists:     attributes["exists"].(bool),
+			hasSSHKeys: optionalBool(d, fmt.Sprintf("user.%d.has_ssh_keys", i)),
+			groups:     expandStringList(attributes["groups"]),
+		}
+		if key := attributes["ssh_key"].(string); key != "" {
+			fingerprint, err := sshKeyFingerprint(key)
+			if err != nil {
+				return expected, fmt.Errorf("user.%d.ssh_key: %v", i, err)
+			}
+			user.sshKey = fingerprint
+		}
+		expected.users = append(expected.users, user)
+	}
+	for _, raw := range d.Get("kernel_argument").([]interface{}) {
+		attributes := raw.(map[string]interface{})
+		expected.kernelArguments = append(expected.kernelArguments, kernelArgumentAssertion{
+			value:   attributes["value"].(string),
+			present: attributes["present"].(bool),
+		})
+	}
+	return expected, nil
+}
+
+// Returns the SHA256 fingerprint of an authorized key, or the fingerprint itself.
+func sshKeyFingerprint(key string) (string, error) {
+	if strings.HasPrefix(key, "SHA256:") {
+		return key, nil
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("invalid SSH public key %q: %v", abbreviateKey(key), err)
+	}
+	return ssh.FingerprintSHA256(publicKey), nil
+}
+
+// Check a config against the assertions. Returns a description of every failed
+// expectation, naming the entry, the expected and the actual value.
+func checkAssertions(config types_v3_4.Config, expected assertions) []string {
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	for _, assertion := range expected.files {
+		failures = append(failures, checkFileAssertion(config, assertion)...)
+	}
+
+	for _, assertion := range expected.units {
+		unit, ok := findUnit(config, assertion.name)
+		switch {
+		case !ok && assertion.exists:
+			fail("unit %s: expected to exist, but the config does not define it", assertion.name)
+			continue
+		case ok && !assertion.exists:
+			fail("unit %s: expected not to exist, but the config defines it", assertion.name)
+			continue
+		case !ok:
+			continue
+		}
+		if assertion.enabled != nil && configutil.BoolValue(unit.Enabled) != *assertion.enabled {
+			fail("unit %s: expected enabled to be %t, got %t", unit.Name, *assertion.enabled, configutil.BoolValue(unit.Enabled))
+		}
+		if assertion.masked != nil && configutil.BoolValue(unit.Mask) != *assertion.masked {
+			fail("unit %s: expected masked to be %t, got %t", unit.Name, *assertion.masked, configutil.BoolValue(unit.Mask))
+		}
+		for _, s := range assertion.contains {
+			if !unitContains(unit, s) {
+				fail("unit %s: expected the contents of the unit or a dropin to contain %q", unit.Name, s)
+			}
+		}
+	}
+
+	for _, assertion := range expected.users {
+		user, ok := findUser(config, assertion.name)
+		switch {
+		case !ok && assertion.exists:
+			fail("user %s: expected to exist, but passwd.users does not define it", assertion.name)
+			continue
+		case ok && !assertion.exists:
+			fail("user %s: expected not to exist, but passwd.users defines it", assertion.name)
+			continue
+		case !ok:
+			continue
+		}
+		if assertion.hasSSHKeys != nil && (len(user.SSHAuthorizedKeys) > 0) != *assertion.hasSSHKeys {
+			fail("user %s: expected has_ssh_keys to be %t, got %d keys", user.Name, *assertion.hasSSHKeys, len(user.SSHAuthorizedKeys))
+		}
+		if assertion.sshKey != "" && !hasSSHKey(user, assertion.sshKey) {
+			fail("user %s: expected an SSH key with fingerprint %s among its %d keys", user.Name, assertion.sshKey, len(user.SSHAuthorizedKeys))
+		}
+		for _, group := range assertion.groups {
+			if !userInGroup(user, group) {
+				fail("user %s: expected to be a member of group %s, got %s", user.Name, group, userGroups(user))
+			}
+		}
+	}
+
+	for _, assertion := range expected.kernelArguments {
+		shouldExist := containsKernelArgument(config.KernelArguments.ShouldExist, assertion.value)
+		shouldNotExist := containsKernelArgument(config.KernelArguments.ShouldNotExist, assertion.value)
+		switch {
+		case assertion.present && !shouldExist:
+			fail("kernel argument %s: expected in kernel_arguments.should_exist", assertion.value)
+		case !assertion.present && !shouldNotExist:
+			fail("kernel argument %s: expected in kernel_arguments.should_not_exist", assertion.value)
+		}
+	}
+	return failures
+}
+
+func checkFileAssertion(config types_v3_4.Config, assertion fileAssertion) []string {
+	var failures []string
+	fail := func(format string, args ...interface{}) {
+		failures = append(failures, fmt.Sprintf("file %s: "+format, append([]interface{}{assertion.path}, args...)...))
+	}
+
+	file, ok := findFile(config, assertion.path)
+	if !ok {
+		if assertion.exists {
+			fail("expected to exist, but the config does not define it%s", otherNodeKind(config, assertion.path))
+		}
+		return failures
+	}
+	if !assertion.exists {
+		fail("expected not to exist, but the config defines it")
+		return failures
+	}
+	if assertion.mode != nil {
+		// Ignition creates files without mode with 0644
+		mode := defaultFileMode
+		if file.Mode != nil {
+			mode = *file.Mode
+		}
+		if mode != *assertion.mode {
+			fail("expected mode %s, got %s", configutil.FormatMode(assertion.mode), configutil.FormatMode(&mode))
+		}
+	}
+	if assertion.owner != "" && !matchesOwner(file.User.Name, file.User.ID, assertion.owner) {
+		fail("expected owner %s, got %s", assertion.owner, ownerName(file.User.Name, file.User.ID))
+	}
+	if assertion.group != "" && !matchesOwner(file.Group.Name, file.Group.ID, assertion.group) {
+		fail("expected group %s, got %s", assertion.group, ownerName(file.Group.Name, file.Group.ID))
+	}
+	if assertion.contentsMatch != nil {
+		var contents []byte
+		for _, resource := range append([]types_v3_4.Resource{file.Contents}, file.Append...) {
+			decoded, ok, err := configutil.DecodeResource(resource)
+			if err != nil {
+				fail("cannot decode contents: %v", err)
+				return failures
+			}
+			if !ok {
+				fail("contents are fetched from %s at boot and cannot be matched", configutil.RemoteSource(resource))
+				return failures
+			}
+			contents = append(contents, decoded...)
+		}
+		if !assertion.contentsMatch.Match(contents) {
+			fail("expected contents to match %s", assertion.contentsMatch)
+		}
+	}
+	return failures
+}
+
+// Hint for paths which are defined as a directory or link instead of a file.
+func otherNodeKind(config types_v3_4.Config, path string) string {
+	for _, directory := range config.Storage.Directories {
+		if directory.Path == path {
+			return ", it is a directory"
+		}
+	}
+	for _, link := range config.Storage.Links {
+		if link.Path == path {
+			return ", it is a link"
+		}
+	}
+	return ""
+}
+
+// Mode of files which do not set one.
+const defaultFileMode = 0o644
+
+// Returns true if an owner given by name or id matches the expected name or id.
+// Nodes without owner are owned by root.
+func matchesOwner(name *string, id *int, expected string) bool {
+	if name == nil && id == nil {
+		return expected == "root" || expected == "0"
+	}
+	return (name != nil && *name == expected) || (id != nil && strconv.Itoa(*id) == expected)
+}
+
+func ownerName(name *string, id *int) string {
+	switch {
+	case name != nil:
+		return *name
+	case id != nil:
+		return strconv.Itoa(*id)
+	}
+	return "root"
+}
+
+func findUnit(config types_v3_4.Config, name string) (types_v3_4.Unit, bool) {
+	for _, unit := range config.Systemd.Units {
+		if unit.Name == name {
+			return unit, true
+		}
+	}
+	return types_v3_4.Unit{}, false
+}
+
+func unitContains(unit types_v3_4.Unit, s string) bool {
+	if strings.Contains(configutil.StringValue(unit.Contents), s) {
+		return true
+	}
+	for _, dropin := range unit.Dropins {
+		if strings.Contains(configutil.StringValue(dropin.Contents), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func findUser(config types_v3_4.Config, name string) (types_v3_4.PasswdUser, bool) {
+	for _, user := range config.Passwd.Users {
+		if user.Name == name {
+			return user, true
+		}
+	}
+	return types_v3_4.PasswdUser{}, false
+}
+
+func hasSSHKey(user types_v3_4.PasswdUser, fingerprint string) bool {
+	for _, key := range user.SSHAuthorizedKeys {
+		if actual, err := sshKeyFingerprint(string(key)); err == nil && actual == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+func userInGroup(user types_v3_4.PasswdUser, group string) bool {
+	if configutil.StringValue(user.PrimaryGroup) == group {
+		return true
+	}
+	for _, g := range user.Groups {
+		if string(g) == group {
+			return true
+		}
+	}
+	return false
+}
+
+func userGroups(user types_v3_4.PasswdUser) string {
+	var groups []string
+	if primary := configutil.StringValue(user.PrimaryGroup); primary != "" {
+		groups = append(groups, primary)
+	}
+	for _, g := range user.Groups {
+		groups = append(groups, string(g))
+	}
+	if len(groups) == 0 {
+		return "no groups"
+	}
+	return strings.Join(groups, ", ")
+}
+
+func containsKernelArgument(arguments []types_v3_4.KernelArgument, value string) bool {
+	for _, argument := range arguments {
+		if string(argument) == value {
+			return true
+		}
+	}
+	return false
+}
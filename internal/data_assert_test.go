@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+const assertConfig = `
+data "ignition_config" "assert" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      groups:
+        - wheel
+      ssh_authorized_keys:
+        - ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGR+R78UWbJfP9oemfHApBkAdUFxrYh4Aryi3v5R0ZnJ alice@example.com
+storage:
+  files:
+    - path: /etc/motd
+      contents:
+        inline: hello world
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+      dropins:
+        - name: override.conf
+          contents: |
+            [Service]
+            Restart=always
+kernel_arguments:
+  should_exist:
+    - quiet
+EOT
+}
+`
+
+const assertResource = assertConfig + `
+data "ignition_assert" "assert" {
+  content = data.ignition_config.assert.rendered
+
+  file {
+    path           = "/etc/motd"
+    mode           = "0644"
+    owner          = "root"
+    contents_match = "^hello"
+  }
+  unit {
+    name     = "docker.service"
+    enabled  = true
+    masked   = false
+    contains = ["Restart=always"]
+  }
+  user {
+    name         = "core"
+    has_ssh_keys = true
+    ssh_key      = "SHA256:L08QmLa/+uJhXStDo1MyFVVeBxUDMxbs0OcaX5yRBto"
+    groups       = ["wheel"]
+  }
+  kernel_argument {
+    value = "quiet"
+  }
+}
+`
+
+const assertFailingResource = assertConfig + `
+data "ignition_assert" "assert" {
+  content = data.ignition_config.assert.rendered
+
+  file {
+    path = "/etc/motd"
+    mode = "0600"
+  }
+  unit {
+    name    = "docker.service"
+    enabled = false
+  }
+}
+`
+
+func TestAssert(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: assertResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_assert.assert", "assertions", "4"),
+				),
+			},
+			{
+				Config:      assertFailingResource,
+				ExpectError: regexp.MustCompile(`(?s)file /etc/motd: expected mode 0600, got 0644.*unit docker.service: expected enabled to be false, got true`),
+			},
+		},
+	})
+}
+
+func TestCheckAssertions(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"passwd": {"users": [{"name": "core", "primaryGroup": "core", "groups": ["wheel"]}]},
+		"storage": {
+			"files": [
+				{"path": "/etc/motd", "mode": 420, "user": {"name": "core"}, "contents": {"source": "data:,hello"}, "append": [{"source": "data:,%20world"}]},
+				{"path": "/etc/remote", "contents": {"source": "https://example.com/remote"}},
+				{"path": "/etc/issue"}
+			],
+			"directories": [{"path": "/etc/app"}]
+		},
+		"systemd": {"units": [{"name": "app.service", "mask": true}]},
+		"kernelArguments": {"shouldNotExist": ["quiet"]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yes, no := true, false
+	mode, defaultMode := 0o600, 0o644
+
+	expected := assertions{
+		files: []fileAssertion{
+			{path: "/etc/motd", exists: true, contentsMatch: regexp.MustCompile("^hello world$"), owner: "core", group: "root"},
+			{path: "/etc/motd", exists: true, mode: &mode, owner: "1000", group: "wheel", contentsMatch: regexp.MustCompile("^bye")},
+			{path: "/etc/remote", exists: true, contentsMatch: regexp.MustCompile(".")},
+			{path: "/etc/issue", exists: true, mode: &defaultMode},
+			{path: "/etc/issue", exists: true, mode: &mode},
+			{path: "/etc/app", exists: true},
+			{path: "/etc/absent", exists: false},
+		},
+		units: []unitAssertion{
+			{name: "app.service", exists: true, enabled: &no, masked: &yes},
+			{name: "app.service", exists: true, enabled: &yes, masked: &no, contains: []string{"ExecStart="}},
+			{name: "docker.service", exists: true},
+		},
+		users: []userAssertion{
+			{name: "core", exists: true, hasSSHKeys: &no, groups: []string{"core", "wheel"}},
+			{name: "core", exists: true, hasSSHKeys: &yes, sshKey: "SHA256:abc", groups: []string{"docker"}},
+			{name: "root", exists: false},
+		},
+		kernelArguments: []kernelArgumentAssertion{
+			{value: "quiet", present: false},
+			{value: "quiet", present: true},
+		},
+	}
+	failures := checkAssertions(config, expected)
+	expectedFailures := []string{
+		"file /etc/motd: expected mode 0600, got 0644",
+		"file /etc/motd: expected owner 1000, got core",
+		"file /etc/motd: expected group wheel, got root",
+		"file /etc/motd: expected contents to match ^bye",
+		"file /etc/remote: contents are fetched from https://example.com/remote at boot and cannot be matched",
+		"file /etc/issue: expected mode 0600, got 0644",
+		"file /etc/app: expected to exist, but the config does not define it, it is a directory",
+		"unit app.service: expected enabled to be true, got false",
+		"unit app.service: expected masked to be false, got true",
+		`unit app.service: expected the contents of the unit or a dropin to contain "ExecStart="`,
+		"unit docker.service: expected to exist, but the config does not define it",
+		"user core: expected has_ssh_keys to be true, got 0 keys",
+		"user core: expected an SSH key with fingerprint SHA256:abc among its 0 keys",
+		"user core: expected to be a member of group docker, got core, wheel",
+		"kernel argument quiet: expected in kernel_arguments.should_exist",
+	}
+	if len(failures) != len(expectedFailures) {
+		t.Fatalf("expected %d failures, got %d: %q", len(expectedFailures), len(failures), failures)
+	}
+	for i := range expectedFailures {
+		if failures[i] != expectedFailures[i] {
+			t.Errorf("expected %q, got %q", expectedFailures[i], failures[i])
+		}
+	}
+}
@@ -2,111 +2,22 @@ package internal
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 
-	butane "github.com/coreos/butane/config"
-	"github.com/coreos/butane/config/common"
-	"github.com/coreos/go-semver/semver"
-	ignition_util "github.com/coreos/ignition/v2/config/util"
-	ignition_v3_0 "github.com/coreos/ignition/v2/config/v3_0"
-	types_v3_0 "github.com/coreos/ignition/v2/config/v3_0/types"
-	ignition_v3_1 "github.com/coreos/ignition/v2/config/v3_1"
-	types_v3_1 "github.com/coreos/ignition/v2/config/v3_1/types"
-	ignition_v3_2 "github.com/coreos/ignition/v2/config/v3_2"
-	types_v3_2 "github.com/coreos/ignition/v2/config/v3_2/types"
-	ignition_v3_3 "github.com/coreos/ignition/v2/config/v3_3"
-	types_v3_3 "github.com/coreos/ignition/v2/config/v3_3/types"
-	ignition_v3_4 "github.com/coreos/ignition/v2/config/v3_4"
-	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
-	"github.com/coreos/vcontext/report"
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
 )
 
-func parseV30(rawConfig []byte) (interface{}, report.Report, error) {
-	return ignition_v3_0.ParseCompatibleVersion(rawConfig)
-}
-
-func mergeV30(parent interface{}, child interface{}) interface{} {
-	return ignition_v3_0.Merge(parent.(types_v3_0.Config), child.(types_v3_0.Config))
-}
-
-func parseV31(rawConfig []byte) (interface{}, report.Report, error) {
-	return ignition_v3_1.ParseCompatibleVersion(rawConfig)
-}
-
-func mergeV31(parent interface{}, child interface{}) interface{} {
-	return ignition_v3_1.Merge(parent.(types_v3_1.Config), child.(types_v3_1.Config))
-}
-
-func parseV32(rawConfig []byte) (interface{}, report.Report, error) {
-	return ignition_v3_2.ParseCompatibleVersion(rawConfig)
-}
-
-func mergeV32(parent interface{}, child interface{}) interface{} {
-	return ignition_v3_2.Merge(parent.(types_v3_2.Config), child.(types_v3_2.Config))
-}
-
-func parseV33(rawConfig []byte) (interface{}, report.Report, error) {
-	return ignition_v3_3.ParseCompatibleVersion(rawConfig)
-}
-
-func mergeV33(parent interface{}, child interface{}) interface{} {
-	return ignition_v3_3.Merge(parent.(types_v3_3.Config), child.(types_v3_3.Config))
-}
-
-func parseV34(rawConfig []byte) (interface{}, report.Report, error) {
-	return ignition_v3_4.ParseCompatibleVersion(rawConfig)
-}
-
-func mergeV34(parent, child interface{}) interface{} {
-	return ignition_v3_4.Merge(parent.(types_v3_4.Config), child.(types_v3_4.Config))
-}
-
-type ignitionInterface struct {
-	Parse func(rawConfig []byte) (interface{}, report.Report, error)
-	Merge func(parent interface{}, child interface{}) interface{}
-}
-
-var versionToLibrary = map[string]ignitionInterface{
-	"3.0.0": {
-		Parse: parseV30,
-		Merge: mergeV30,
-	},
-	"3.1.0": {
-		Parse: parseV31,
-		Merge: mergeV31,
-	},
-	"3.2.0": {
-		Parse: parseV32,
-		Merge: mergeV32,
-	},
-	"3.3.0": {
-		Parse: parseV33,
-		Merge: mergeV33,
-	},
-	"3.4.0": {
-		Parse: parseV34,
-		Merge: mergeV34,
-	},
-}
-
-func getLibraryForVersion(version string) (ignitionInterface, error) {
-	ignition, ok := versionToLibrary[version]
-	if !ok {
-		return ignitionInterface{}, errors.New("incompatible version")
-	}
-	return ignition, nil
-}
-
 func datasourceConfig() *schema.Resource {
 	return &schema.Resource{
 		ReadContext: datasourceConfigRead,
 
-		Schema: map[string]*schema.Schema{
+		Schema: renderOptionsSchema(map[string]*schema.Schema{
 			"content": {
 				Type:     schema.TypeString,
 				Required: true,
@@ -119,128 +30,242 @@ func datasourceConfig() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
-			"pretty_print": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
-			},
-			"strict": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
-			},
 			"rendered": {
 				Type:        schema.TypeString,
 				Computed:    true,
 				Description: "rendered ignition configuration",
 			},
-		},
+			"unit_graph": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "dependency graph of the systemd units in the DOT language",
+			},
+			"storage_layout": computedList(map[string]*schema.Schema{
+				"device":    computedString(),
+				"number":    computedInt(),
+				"label":     computedString(),
+				"start_mib": computedInt(),
+				"size_mib":  computedInt(),
+			}),
+		}),
 	}
 }
 
-func datasourceConfigRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
-	var diags diag.Diagnostics
+// Arguments controlling how content and snippets are merged and checked,
+// shared by ignition_config and ignition_config_set.
+func renderOptionsSchema(elem map[string]*schema.Schema) map[string]*schema.Schema {
+	elem["pretty_print"] = &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	}
+	elem["strict"] = &schema.Schema{
+		Type:     schema.TypeBool,
+		Optional: true,
+		Default:  false,
+	}
+	elem["files_dir"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "directory of local files embedded by content and snippets, e.g. with contents.local",
+	}
+	elem["version_strategy"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      string(render.VersionContent),
+		ValidateFunc: validation.StringInSlice([]string{string(render.VersionContent), string(render.VersionNewest), string(render.VersionLatest)}, false),
+		Description:  "Ignition spec version of the rendered config: the version of content, the newest of all inputs or the latest supported",
+	}
+	elem["conflict_policy"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      string(render.ConflictLastWins),
+		ValidateFunc: validation.StringInSlice([]string{string(render.ConflictLastWins), string(render.ConflictWarn), string(render.ConflictError)}, false),
+		Description:  "how entries defined by more than one of content and snippets are reported",
+	}
+	elem["lint_units"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: "report syntax and semantic problems of unit and dropin contents as warnings",
+	}
+	elem["validate_paths"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: "check files, directories and links against the writable locations of the variant",
+	}
+	elem["validate_accounts"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: "check users and groups referenced by files, units and passwd against passwd and the variant",
+	}
+	elem["validate_files"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     false,
+		Description: "validate the syntax of inline JSON, YAML, TOML and INI files",
+	}
+	elem["file_formats"] = &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "formats of files to validate by path or path pattern, e.g. {\"/etc/app/*.conf\" = \"toml\"}",
+	}
+	elem["validate_storage"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Default:     true,
+		Description: "check the partition layout and device references of the storage section",
+	}
+	elem["check_severity"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      string(render.SeverityWarning),
+		ValidateFunc: validation.StringInSlice([]string{string(render.SeverityWarning), string(render.SeverityError)}, false),
+		Description:  "severity of the problems found by validate_paths, validate_accounts and validate_storage",
+	}
+	elem["disk_sizes"] = &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeInt},
+		Description: "sizes of disks in MiB by device, used to compute the partition layout",
+	}
+	elem["scan_secrets"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      string(render.SecretScanOff),
+		ValidateFunc: validation.StringInSlice([]string{string(render.SecretScanOff), string(render.SecretScanWarn), string(render.SecretScanError)}, false),
+		Description:  "how private keys, credentials and tokens in file and unit contents are reported",
+	}
+	elem["secret_allowlist"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "file paths, unit names or patterns of them which may contain secrets",
+	}
+	elem["rule"] = ruleSchema()
+	return elem
+}
 
-	renderedBytes, err := renderConfig(d)
-	if err != nil {
-		return diag.FromErr(err)
+func datasourceConfigRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	start := time.Now()
+	result, diags := renderConfig(ctx, d, meta)
+	fields := diagnosticFields(diags)
+	fields["duration_ms"] = milliseconds(time.Since(start))
+	if diags.HasError() {
+		tflog.Debug(ctx, "failed to render ignition_config", fields)
+		return diags
 	}
-	rendered := string(renderedBytes)
+	rendered := string(result.Rendered)
 
 	if err := d.Set("rendered", rendered); err != nil {
-		return diag.FromErr(err)
+		return append(diags, diag.FromErr(err)...)
+	}
+	if err := d.Set("unit_graph", result.UnitGraph); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	if err := d.Set("storage_layout", flattenStorageLayout(result.StorageLayout)); err != nil {
+		return append(diags, diag.FromErr(err)...)
 	}
 	d.SetId(hashcode(rendered))
+	fields["rendered_bytes"] = len(rendered)
+	tflog.Debug(ctx, "rendered ignition_config", fields)
 	return diags
 }
 
-type getConfigVersion func(ignition []byte) (semver.Version, error)
-
-func renderConfig(d *schema.ResourceData) ([]byte, error) {
-	// unchecked assertions seem to be the norm in Terraform :S
-	content := d.Get("content").(string)
-	pretty := d.Get("pretty_print").(bool)
-	strict := d.Get("strict").(bool)
-	snippetsIface := d.Get("snippets").([]interface{})
-
-	snippets := make([]string, len(snippetsIface))
-	for i, v := range snippetsIface {
-		if v == nil {
-			continue
+func flattenStorageLayout(layout []render.Partition) []interface{} {
+	partitions := make([]interface{}, len(layout))
+	for i, partition := range layout {
+		partitions[i] = map[string]interface{}{
+			"device":    partition.Device,
+			"number":    partition.Number,
+			"label":     partition.Label,
+			"start_mib": partition.StartMiB,
+			"size_mib":  partition.SizeMiB,
 		}
-		snippets[i] = v.(string)
 	}
+	return partitions
+}
 
-	// transpile content
-	ignitionConfig, contentVersion, ignition, err := transpileButane(
-		content,
-		strict,
-		func(ignitionBytes []byte) (semver.Version, error) {
-			version, _, err := ignition_util.GetConfigVersion(ignitionBytes)
-			return version, err
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("content parse error: %v", err)
+// Render options as set by the arguments of renderOptionsSchema, without content and snippets.
+func expandRenderOptions(d *schema.ResourceData, meta interface{}) (render.Options, error) {
+	// unchecked assertions seem to be the norm in Terraform :S
+	options := render.Options{
+		Strict:           d.Get("strict").(bool),
+		PrettyPrint:      d.Get("pretty_print").(bool),
+		FilesDir:         d.Get("files_dir").(string),
+		VersionStrategy:  render.VersionStrategy(d.Get("version_strategy").(string)),
+		ConflictPolicy:   render.ConflictPolicy(d.Get("conflict_policy").(string)),
+		LintUnits:        d.Get("lint_units").(bool),
+		ValidatePaths:    d.Get("validate_paths").(bool),
+		ValidateAccounts: d.Get("validate_accounts").(bool),
+		ValidateFiles:    d.Get("validate_files").(bool),
+		FileFormats:      map[string]string{},
+		ValidateStorage:  d.Get("validate_storage").(bool),
+		CheckSeverity:    render.Severity(d.Get("check_severity").(string)),
+		DiskSizes:        map[string]int{},
+		ScanSecrets:      render.SecretScan(d.Get("scan_secrets").(string)),
 	}
-
-	// transpile snippets and merge them with content
-	for _, snippet := range snippets {
-		snippetIgnitionConfig, _, _, err := transpileButane(snippet, strict, ensureMaxVersion(contentVersion))
-		if err != nil {
-			return nil, fmt.Errorf("snippet parse error: %v", err)
+	for _, pattern := range d.Get("secret_allowlist").([]interface{}) {
+		if pattern != nil {
+			options.SecretAllowlist = append(options.SecretAllowlist, pattern.(string))
 		}
-		ignitionConfig = ignition.Merge(ignitionConfig, snippetIgnitionConfig)
-	}
-
-	// marshal json
-	if pretty {
-		return json.MarshalIndent(ignitionConfig, "", "  ")
 	}
-	return json.Marshal(ignitionConfig)
-}
-
-// Transpile Butane into a Ignition configuration object determined by the Ignitition version given.
-// Returns the Ignition configuration object, the Ignition version used and the matching Ignition library
-func transpileButane(
-	butaneConfig string,
-	strict bool,
-	getIgnitionVersion getConfigVersion,
-) (interface{}, semver.Version, ignitionInterface, error) {
-	ignitionBytes, report, err := butane.TranslateBytes([]byte(butaneConfig), common.TranslateBytesOptions{})
-	if err != nil {
-		return nil, semver.Version{}, ignitionInterface{}, err
+	for device, size := range d.Get("disk_sizes").(map[string]interface{}) {
+		options.DiskSizes[device] = size.(int)
 	}
-	if strict && len(report.Entries) > 0 {
-		return nil, semver.Version{}, ignitionInterface{}, fmt.Errorf("strict parsing error: %v", report.String())
+	for pattern, format := range d.Get("file_formats").(map[string]interface{}) {
+		options.FileFormats[pattern] = format.(string)
 	}
-	version, err := getIgnitionVersion(ignitionBytes)
+	datasourceRules, err := expandRules(d.Get("rule").([]interface{}))
 	if err != nil {
-		return nil, semver.Version{}, ignitionInterface{}, err
+		return options, err
 	}
-	ignition, err := getLibraryForVersion(version.String())
+	options.Rules = mergeRules(providerRules(meta), datasourceRules)
+	return options, nil
+}
+
+// Render the content and snippets of an ignition_config.
+func renderConfig(ctx context.Context, d *schema.ResourceData, meta interface{}) (render.Result, diag.Diagnostics) {
+	options, err := expandRenderOptions(d, meta)
 	if err != nil {
-		return nil, semver.Version{}, ignitionInterface{}, err
+		return render.Result{}, diag.FromErr(err)
 	}
-	ignitionConfig, _, err := ignition.Parse(ignitionBytes)
+	options.Content = d.Get("content").(string)
+	snippetsIface := d.Get("snippets").([]interface{})
+
+	options.Snippets = make([]string, len(snippetsIface))
+	for i, v := range snippetsIface {
+		if v == nil {
+			continue
+		}
+		options.Snippets[i] = v.(string)
+	}
+	return renderDiagnostics(render.Render(ctx, options))
+}
 
-	return ignitionConfig, version, ignition, err
+// Convert the result of a render to Terraform diagnostics, keeping the warnings of failed renders.
+func renderDiagnostics(result render.Result, err error) (render.Result, diag.Diagnostics) {
+	var renderErr *render.Error
+	switch {
+	case errors.As(err, &renderErr):
+		return result, convertDiagnostics(renderErr.Diagnostics)
+	case err != nil:
+		return result, diag.FromErr(err)
+	}
+	return result, convertDiagnostics(result.Warnings)
 }
 
-// prepare function to validate snippets against
-func ensureMaxVersion(maxVersion semver.Version) getConfigVersion {
-	return func(ignitionBytes []byte) (semver.Version, error) {
-		version, _, err := ignition_util.GetConfigVersion(ignitionBytes)
-		if err != nil {
-			return semver.Version{}, err
-		}
-		if maxVersion.LessThan(version) {
-			return semver.Version{}, fmt.Errorf(
-				"version %s is newer than max version %s and therefore incompatible",
-				version.String(),
-				maxVersion.String(),
-			)
+func convertDiagnostics(diags render.Diagnostics) diag.Diagnostics {
+	var converted diag.Diagnostics
+	for _, d := range diags {
+		severity := diag.Warning
+		if d.Severity == render.SeverityError {
+			severity = diag.Error
 		}
-		return maxVersion, nil
+		converted = append(converted, diag.Diagnostic{Severity: severity, Summary: d.Summary, Detail: d.Detail})
 	}
+	return converted
 }
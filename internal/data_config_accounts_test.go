@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const accountsResource = `
+data "ignition_config" "accounts" {
+  check_severity = "error"
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/app.conf
+      user:
+        name: app
+      contents:
+        inline: app
+EOT
+}
+`
+
+const accountsCreatedResource = `
+data "ignition_config" "accounts" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: app
+storage:
+  files:
+    - path: /etc/app.conf
+      user:
+        name: app
+      group:
+        name: app
+      contents:
+        inline: app
+EOT
+}
+`
+
+func TestAccounts(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config:      accountsResource,
+				ExpectError: regexp.MustCompile(`file /etc/app.conf \(content\): user app is neither created in passwd.users nor built into fcos`),
+			},
+			{
+				Config: accountsCreatedResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.accounts", "rendered"),
+				),
+			},
+		},
+	})
+}
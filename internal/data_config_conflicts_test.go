@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const conflictsContent = `
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+storage:
+  disks:
+    - device: /dev/vdb
+      partitions:
+        - number: 1
+          label: data
+  files:
+    - path: /etc/motd
+      contents:
+        inline: content
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+`
+
+const conflictsSnippet = `
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key
+storage:
+  disks:
+    - device: /dev/vdb
+      partitions:
+        - number: 1
+          label: other
+  directories:
+    - path: /etc/motd
+systemd:
+  units:
+    - name: docker.service
+      dropins:
+        - name: 10-env.conf
+          contents: |
+            [Service]
+            Environment=A=1
+`
+
+func conflictsResource(policy string) string {
+	return `
+data "ignition_config" "conflicts" {
+  conflict_policy = "` + policy + `"
+  content = <<EOT` + conflictsContent + `EOT
+  snippets = [
+<<EOT` + conflictsSnippet + `EOT
+  ]
+}
+`
+}
+
+func TestConflictPolicy(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: conflictsResource("last_wins"),
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.conflicts", "rendered"),
+				),
+			},
+			{
+				Config: conflictsResource("warn"),
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.conflicts", "rendered"),
+				),
+			},
+			{
+				Config:      conflictsResource("error"),
+				ExpectError: regexp.MustCompile(`directory /etc/motd is defined in content and snippet 0`),
+			},
+		},
+	})
+}
@@ -0,0 +1,517 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func datasourceConfigDiff() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceConfigDiffRead,
+
+		Schema: map[string]*schema.Schema{
+			"old": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "previously rendered ignition configuration",
+			},
+			"new": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "newly rendered ignition configuration",
+			},
+			"changed":          computedBool(),
+			"old_version":      computedString(),
+			"new_version":      computedString(),
+			"files_added":      computedStringList(),
+			"files_removed":    computedStringList(),
+			"files_changed":    computedStringList(),
+			"units_added":      computedStringList(),
+			"units_removed":    computedStringList(),
+			"units_changed":    computedStringList(),
+			"users_added":      computedStringList(),
+			"users_removed":    computedStringList(),
+			"users_changed":    computedStringList(),
+			"groups_added":     computedStringList(),
+			"groups_removed":   computedStringList(),
+			"groups_changed":   computedStringList(),
+			"ssh_keys_added":   computedStringList(),
+			"ssh_keys_removed": computedStringList(),
+			"storage_changes":  computedStringList(),
+			"file_diffs": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "unified diffs of changed text file contents, keyed by path",
+			},
+			"unit_diffs": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "unified diffs of changed unit and dropin contents, keyed by unit name",
+			},
+			"summary": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "human-readable summary of all changes",
+			},
+		},
+	}
+}
+
+func datasourceConfigDiffRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	oldContent := d.Get("old").(string)
+	newContent := d.Get("new").(string)
+	oldConfig, err := configutil.ParseRendered([]byte(oldContent))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("old parse error: %v", err))
+	}
+	newConfig, err := configutil.ParseRendered([]byte(newContent))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("new parse error: %v", err))
+	}
+
+	oldVersion, _, _ := ignition_util.GetConfigVersion([]byte(oldContent))
+	newVersion, _, _ := ignition_util.GetConfigVersion([]byte(newContent))
+	diff := diffConfigs(oldConfig, newConfig)
+	diff.oldVersion = oldVersion.String()
+	diff.newVersion = newVersion.String()
+
+	attributes := map[string]interface{}{
+		"changed":          diff.changed || diff.oldVersion != diff.newVersion,
+		"old_version":      diff.oldVersion,
+		"new_version":      diff.newVersion,
+		"files_added":      diff.files.added,
+		"files_removed":    diff.files.removed,
+		"files_changed":    diff.files.changedKeys(),
+		"units_added":      diff.units.added,
+		"units_removed":    diff.units.removed,
+		"units_changed":    diff.units.changedKeys(),
+		"users_added":      diff.users.added,
+		"users_removed":    diff.users.removed,
+		"users_changed":    diff.users.changedKeys(),
+		"groups_added":     diff.groups.added,
+		"groups_removed":   diff.groups.removed,
+		"groups_changed":   diff.groups.changedKeys(),
+		"ssh_keys_added":   diff.sshKeysAdded,
+		"ssh_keys_removed": diff.sshKeysRemoved,
+		"storage_changes":  diff.storage,
+		"file_diffs":       diff.fileDiffs,
+		"unit_diffs":       diff.unitDiffs,
+		"summary":          diff.summary(),
+	}
+	for key, value := range attributes {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	d.SetId(hashcode(oldContent + newContent))
+	return diags
+}
+
+// Changes of one kind of keyed config entries, e.g. files by path.
+type entryChanges struct {
+	added   []string
+	removed []string
+	// changed entries with the names of the fields which differ
+	changed map[string][]string
+}
+
+func (c entryChanges) changedKeys() []string {
+	keys := make([]string, 0, len(c.changed))
+	for key := range c.changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type configDiff struct {
+	changed        bool
+	oldVersion     string
+	newVersion     string
+	files          entryChanges
+	units          entryChanges
+	users          entryChanges
+	groups         entryChanges
+	sshKeysAdded   []string
+	sshKeysRemoved []string
+	storage        []string
+	fileDiffs      map[string]string
+	unitDiffs      map[string]string
+}
+
+// Compare two configs, which have to be translated to the same spec beforehand. Inline
+// contents are compared decoded, so changing only their encoding or compression is no change.
+func diffConfigs(oldConfig, newConfig types_v3_4.Config) configDiff {
+	oldConfig, newConfig = configutil.Canonicalize(oldConfig), configutil.Canonicalize(newConfig)
+	oldConfig.Ignition.Version = ""
+	newConfig.Ignition.Version = ""
+	diff := configDiff{
+		changed:   !reflect.DeepEqual(oldConfig, newConfig),
+		fileDiffs: map[string]string{},
+		unitDiffs: map[string]string{},
+	}
+
+	diff.files = diffEntries(storageNodes(oldConfig), storageNodes(newConfig), diffNodes)
+	for _, path := range diff.files.changedKeys() {
+		oldFile, oldOk := findFile(oldConfig, path)
+		newFile, newOk := findFile(newConfig, path)
+		if !oldOk || !newOk {
+			continue
+		}
+		oldContents, oldText := textContents(oldFile.Contents)
+		newContents, newText := textContents(newFile.Contents)
+		if oldText && newText && oldContents != newContents {
+			diff.fileDiffs[path] = unifiedDiff(path, oldContents, newContents)
+		}
+	}
+
+	diff.units = diffEntries(keyedUnits(oldConfig), keyedUnits(newConfig), diffFields)
+	for _, name := range diff.units.changedKeys() {
+		oldUnit := keyedUnits(oldConfig)[name].(types_v3_4.Unit)
+		newUnit := keyedUnits(newConfig)[name].(types_v3_4.Unit)
+		diff.unitDiffs[name] = unitContentsDiff(oldUnit, newUnit)
+		if diff.unitDiffs[name] == "" {
+			delete(diff.unitDiffs, name)
+		}
+	}
+
+	oldUsers, newUsers := keyedUsers(oldConfig), keyedUsers(newConfig)
+	diff.users = diffEntries(oldUsers, newUsers, diffPasswdEntries)
+	for _, name := range configutil.SortedKeys(oldUsers, newUsers) {
+		var oldKeys, newKeys []types_v3_4.SSHAuthorizedKey
+		if user, ok := oldUsers[name]; ok {
+			oldKeys = user.(types_v3_4.PasswdUser).SSHAuthorizedKeys
+		}
+		if user, ok := newUsers[name]; ok {
+			newKeys = user.(types_v3_4.PasswdUser).SSHAuthorizedKeys
+		}
+		added, removed := diffKeys(oldKeys, newKeys)
+		for _, key := range added {
+			diff.sshKeysAdded = append(diff.sshKeysAdded, fmt.Sprintf("%s: %s", name, key))
+		}
+		for _, key := range removed {
+			diff.sshKeysRemoved = append(diff.sshKeysRemoved, fmt.Sprintf("%s: %s", name, key))
+		}
+	}
+
+	diff.groups = diffEntries(keyedGroups(oldConfig), keyedGroups(newConfig), diffPasswdEntries)
+	diff.storage = diffStorage(oldConfig.Storage, newConfig.Storage)
+	return diff
+}
+
+func (diff configDiff) summary() string {
+	var b strings.Builder
+	if diff.oldVersion != diff.newVersion {
+		fmt.Fprintf(&b, "~ version %s -> %s\n", diff.oldVersion, diff.newVersion)
+	}
+	writeChanges := func(kind string, changes entryChanges) {
+		for _, key := range changes.added {
+			fmt.Fprintf(&b, "+ %s %s\n", kind, key)
+		}
+		for _, key := range changes.removed {
+			fmt.Fprintf(&b, "- %s %s\n", kind, key)
+		}
+		for _, key := range changes.changedKeys() {
+			fmt.Fprintf(&b, "~ %s %s (%s)\n", kind, key, strings.Join(changes.changed[key], ", "))
+		}
+	}
+	writeChanges("file", diff.files)
+	writeChanges("unit", diff.units)
+	writeChanges("user", diff.users)
+	for _, key := range diff.sshKeysAdded {
+		fmt.Fprintf(&b, "+ ssh key %s\n", key)
+	}
+	for _, key := range diff.sshKeysRemoved {
+		fmt.Fprintf(&b, "- ssh key %s\n", key)
+	}
+	writeChanges("group", diff.groups)
+	for _, change := range diff.storage {
+		fmt.Fprintf(&b, "~ storage %s\n", change)
+	}
+	if b.Len() == 0 && diff.changed {
+		b.WriteString("~ ignition settings changed\n")
+	}
+	for _, path := range configutil.SortedKeys(diff.fileDiffs) {
+		b.WriteString("\n" + diff.fileDiffs[path])
+	}
+	for _, name := range configutil.SortedKeys(diff.unitDiffs) {
+		b.WriteString("\n" + diff.unitDiffs[name])
+	}
+	return b.String()
+}
+
+// Compare keyed entries. The compare function returns the names of the fields which differ.
+func diffEntries(oldEntries, newEntries map[string]interface{}, compare func(a, b interface{}) []string) entryChanges {
+	changes := entryChanges{changed: map[string][]string{}}
+	for _, key := range configutil.SortedKeys(oldEntries, newEntries) {
+		oldEntry, oldOk := oldEntries[key]
+		newEntry, newOk := newEntries[key]
+		switch {
+		case !oldOk:
+			changes.added = append(changes.added, key)
+		case !newOk:
+			changes.removed = append(changes.removed, key)
+		default:
+			if fields := compare(oldEntry, newEntry); len(fields) > 0 {
+				changes.changed[key] = fields
+			}
+		}
+	}
+	return changes
+}
+
+// Names of the JSON fields in which two structs of the same type differ.
+func diffFields(a, b interface{}) []string {
+	var fields []string
+	aValue, bValue := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < aValue.NumField(); i++ {
+		field := aValue.Type().Field(i)
+		if field.Anonymous {
+			fields = append(fields, diffFields(aValue.Field(i).Interface(), bValue.Field(i).Interface())...)
+			continue
+		}
+		if !reflect.DeepEqual(aValue.Field(i).Interface(), bValue.Field(i).Interface()) {
+			fields = append(fields, strings.Split(field.Tag.Get("json"), ",")[0])
+		}
+	}
+	return fields
+}
+
+func diffNodes(a, b interface{}) []string {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return []string{"type"}
+	}
+	return diffFields(a, b)
+}
+
+func diffPasswdEntries(a, b interface{}) []string {
+	var fields []string
+	for _, field := range diffFields(a, b) {
+		if field == "passwordHash" {
+			// never expose hashes, only report that it changed
+			field = "password"
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func diffKeys(oldKeys, newKeys []types_v3_4.SSHAuthorizedKey) (added, removed []string) {
+	oldSet := map[types_v3_4.SSHAuthorizedKey]bool{}
+	for _, key := range oldKeys {
+		oldSet[key] = true
+	}
+	newSet := map[types_v3_4.SSHAuthorizedKey]bool{}
+	for _, key := range newKeys {
+		newSet[key] = true
+		if !oldSet[key] {
+			added = append(added, string(key))
+		}
+	}
+	for _, key := range oldKeys {
+		if !newSet[key] {
+			removed = append(removed, string(key))
+		}
+	}
+	return added, removed
+}
+
+func diffStorage(oldStorage, newStorage types_v3_4.Storage) []string {
+	var changes []string
+	describe := func(kind string, oldEntries, newEntries map[string]interface{}) {
+		entries := diffEntries(oldEntries, newEntries, diffFields)
+		for _, key := range entries.added {
+			changes = append(changes, fmt.Sprintf("%s %s added", kind, key))
+		}
+		for _, key := range entries.removed {
+			changes = append(changes, fmt.Sprintf("%s %s removed", kind, key))
+		}
+		for _, key := range entries.changedKeys() {
+			changes = append(changes, fmt.Sprintf("%s %s changed (%s)", kind, key, strings.Join(entries.changed[key], ", ")))
+		}
+	}
+
+	oldDisks, newDisks := map[string]interface{}{}, map[string]interface{}{}
+	oldPartitions, newPartitions := map[string]interface{}{}, map[string]interface{}{}
+	for _, disk := range oldStorage.Disks {
+		oldDisks[disk.Device] = types_v3_4.Disk{Device: disk.Device, WipeTable: disk.WipeTable}
+		for key, partition := range keyedPartitions(disk) {
+			oldPartitions[key] = partition
+		}
+	}
+	for _, disk := range newStorage.Disks {
+		newDisks[disk.Device] = types_v3_4.Disk{Device: disk.Device, WipeTable: disk.WipeTable}
+		for key, partition := range keyedPartitions(disk) {
+			newPartitions[key] = partition
+		}
+	}
+	describe("disk", oldDisks, newDisks)
+	describe("partition", oldPartitions, newPartitions)
+
+	oldRaid, newRaid := map[string]interface{}{}, map[string]interface{}{}
+	for _, raid := range oldStorage.Raid {
+		oldRaid[raid.Name] = raid
+	}
+	for _, raid := range newStorage.Raid {
+		newRaid[raid.Name] = raid
+	}
+	describe("raid", oldRaid, newRaid)
+
+	oldLuks, newLuks := map[string]interface{}{}, map[string]interface{}{}
+	for _, luks := range oldStorage.Luks {
+		oldLuks[luks.Name] = luks
+	}
+	for _, luks := range newStorage.Luks {
+		newLuks[luks.Name] = luks
+	}
+	describe("luks", oldLuks, newLuks)
+
+	oldFilesystems, newFilesystems := map[string]interface{}{}, map[string]interface{}{}
+	for _, filesystem := range oldStorage.Filesystems {
+		oldFilesystems[filesystem.Device] = filesystem
+	}
+	for _, filesystem := range newStorage.Filesystems {
+		newFilesystems[filesystem.Device] = filesystem
+	}
+	describe("filesystem", oldFilesystems, newFilesystems)
+	return changes
+}
+
+// Partitions are keyed by number, or by label if the number is left to sgdisk.
+func keyedPartitions(disk types_v3_4.Disk) map[string]interface{} {
+	partitions := map[string]interface{}{}
+	for _, partition := range disk.Partitions {
+		key := fmt.Sprintf("%d on %s", partition.Number, disk.Device)
+		if partition.Number == 0 {
+			key = fmt.Sprintf("%s on %s", configutil.StringValue(partition.Label), disk.Device)
+		}
+		partitions[key] = partition
+	}
+	return partitions
+}
+
+// Files, directories and links keyed by path.
+func storageNodes(config types_v3_4.Config) map[string]interface{} {
+	nodes := map[string]interface{}{}
+	for _, file := range config.Storage.Files {
+		nodes[file.Path] = file
+	}
+	for _, directory := range config.Storage.Directories {
+		nodes[directory.Path] = directory
+	}
+	for _, link := range config.Storage.Links {
+		nodes[link.Path] = link
+	}
+	return nodes
+}
+
+func findFile(config types_v3_4.Config, path string) (types_v3_4.File, bool) {
+	for _, file := range config.Storage.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return types_v3_4.File{}, false
+}
+
+func keyedUnits(config types_v3_4.Config) map[string]interface{} {
+	units := map[string]interface{}{}
+	for _, unit := range config.Systemd.Units {
+		units[unit.Name] = unit
+	}
+	return units
+}
+
+func keyedUsers(config types_v3_4.Config) map[string]interface{} {
+	users := map[string]interface{}{}
+	for _, user := range config.Passwd.Users {
+		users[user.Name] = user
+	}
+	return users
+}
+
+func keyedGroups(config types_v3_4.Config) map[string]interface{} {
+	groups := map[string]interface{}{}
+	for _, group := range config.Passwd.Groups {
+		groups[group.Name] = group
+	}
+	return groups
+}
+
+// Decoded contents of an inline resource, if it is text.
+func textContents(resource types_v3_4.Resource) (string, bool) {
+	contents, ok, err := configutil.DecodeResource(resource)
+	if err != nil || !ok || !utf8.Valid(contents) {
+		return "", false
+	}
+	return string(contents), true
+}
+
+func unitContentsDiff(oldUnit, newUnit types_v3_4.Unit) string {
+	var b strings.Builder
+	if oldContents, newContents := configutil.StringValue(oldUnit.Contents), configutil.StringValue(newUnit.Contents); oldContents != newContents {
+		b.WriteString(unifiedDiff(newUnit.Name, oldContents, newContents))
+	}
+	oldDropins := map[string]string{}
+	for _, dropin := range oldUnit.Dropins {
+		oldDropins[dropin.Name] = configutil.StringValue(dropin.Contents)
+	}
+	newDropins := map[string]string{}
+	for _, dropin := range newUnit.Dropins {
+		newDropins[dropin.Name] = configutil.StringValue(dropin.Contents)
+	}
+	for _, name := range configutil.SortedKeys(oldDropins, newDropins) {
+		if oldDropins[name] != newDropins[name] {
+			b.WriteString(unifiedDiff(newUnit.Name+".d/"+name, oldDropins[name], newDropins[name]))
+		}
+	}
+	return b.String()
+}
+
+func unifiedDiff(name, oldText, newText string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldText),
+		B:        splitLines(newText),
+		FromFile: "a" + ensureLeadingSlash(name),
+		ToFile:   "b" + ensureLeadingSlash(name),
+		Context:  3,
+	})
+	return diff
+}
+
+// Split text into lines keeping the line endings, the last line is terminated if necessary.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+func ensureLeadingSlash(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/" + name
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+const configDiffResource = `
+data "ignition_config" "old" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.4.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key-old
+storage:
+  files:
+    - path: /etc/motd
+      contents:
+        inline: |
+          hello
+          world
+    - path: /etc/removed
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+EOT
+}
+
+data "ignition_config" "new" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key-new
+storage:
+  disks:
+    - device: /dev/vdb
+      wipe_table: true
+  files:
+    - path: /etc/motd
+      contents:
+        inline: |
+          hello
+          there
+    - path: /etc/added
+systemd:
+  units:
+    - name: docker.service
+      enabled: false
+EOT
+}
+
+data "ignition_config_diff" "diff" {
+  old = data.ignition_config.old.rendered
+  new = data.ignition_config.new.rendered
+}
+
+data "ignition_config_diff" "unchanged" {
+  old = data.ignition_config.new.rendered
+  new = data.ignition_config.new.rendered
+}
+`
+
+func TestConfigDiff(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: configDiffResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "changed", "true"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "old_version", "3.3.0"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "new_version", "3.4.0"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "files_added.0", "/etc/added"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "files_removed.0", "/etc/removed"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "files_changed.0", "/etc/motd"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "file_diffs./etc/motd", "--- a/etc/motd\n+++ b/etc/motd\n@@ -1,2 +1,2 @@\n hello\n-world\n+there\n"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "units_changed.0", "docker.service"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "users_changed.0", "core"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "ssh_keys_added.0", "core: key-new"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "ssh_keys_removed.0", "core: key-old"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.diff", "storage_changes.0", "disk /dev/vdb added"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.unchanged", "changed", "false"),
+					r.TestCheckResourceAttr("data.ignition_config_diff.unchanged", "summary", ""),
+				),
+			},
+		},
+	})
+}
+
+func TestDiffConfigsEncoding(t *testing.T) {
+	var configs []types_v3_4.Config
+	for _, source := range []string{
+		`{"source": "data:,hello"}`,
+		`{"source": "data:;base64,aGVsbG8="}`,
+		`{"source": "data:;base64,H4sIAAAAAAAC/8tIzcnJBwCGphA2BQAAAA==", "compression": "gzip"}`,
+		`{"source": "data:,hello%20world"}`,
+	} {
+		config, err := configutil.ParseRendered([]byte(`{"ignition": {"version": "3.4.0"}, "storage": {"files": [{"path": "/etc/motd", "contents": ` + source + `}]}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, config)
+	}
+	for _, config := range configs[1:3] {
+		if diff := diffConfigs(configs[0], config); diff.changed || diff.summary() != "" {
+			t.Errorf("expected no changes for another encoding, got %q", diff.summary())
+		}
+	}
+	if diff := diffConfigs(configs[2], configs[3]); !diff.changed || !strings.HasPrefix(diff.summary(), "~ file /etc/motd (contents)\n") {
+		t.Errorf("expected changed contents, got %q", diff.summary())
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
 
@@ -1268,3 +1269,45 @@ func TestUnsupportedVersion(t *testing.T) {
 		},
 	})
 }
+
+// Version strategies
+
+const versionStrategyResource = `
+data "ignition_config" "version-strategy" {
+  version_strategy = "%s"
+  content = <<EOT
+---
+variant: fcos
+version: 1.4.0
+EOT
+	snippets = [
+<<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+	]
+}
+`
+
+func TestVersionStrategy(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config:      fmt.Sprintf(versionStrategyResource, "content"),
+				ExpectError: regexp.MustCompile("newer than max version 3.3.0"),
+			},
+			{
+				Config: fmt.Sprintf(versionStrategyResource, "newest"),
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_config.version-strategy", "rendered", regexp.MustCompile(`"version":"3.4.0"`)),
+				),
+			},
+			{
+				Config:      fmt.Sprintf(versionStrategyResource, "oldest"),
+				ExpectError: regexp.MustCompile(`expected version_strategy to be one of`),
+			},
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const fileValidationContent = `
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/containers/registries.conf
+      contents:
+        inline: |
+          unqualified-search-registries = ["docker.io"]
+    - path: /etc/docker/daemon.json
+      contents:
+        inline: |
+          {"log-driver": "journald"}
+    - path: /etc/NetworkManager/system-connections/eth0.nmconnection
+      mode: 0600
+      contents:
+        inline: |
+          [connection]
+          id=eth0
+          type=ethernet
+`
+
+const fileValidationSnippet = `
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/app/settings.conf
+      contents:
+        inline: |
+          [server]
+          port = 8080
+          host = "localhost
+`
+
+const fileValidationResource = `
+data "ignition_config" "files" {
+  validate_files = true
+  content = <<EOT` + fileValidationContent + `EOT
+}
+`
+
+const fileValidationInvalidResource = `
+data "ignition_config" "files" {
+  validate_files = true
+  file_formats = {
+    "/etc/app/*.conf" = "toml"
+  }
+  content = <<EOT` + fileValidationContent + `EOT
+  snippets = [
+<<EOT` + fileValidationSnippet + `EOT
+  ]
+}
+`
+
+func TestFileValidation(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: fileValidationResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.files", "rendered"),
+				),
+			},
+			{
+				Config:      fileValidationInvalidResource,
+				ExpectError: regexp.MustCompile(`file /etc/app/settings.conf \(snippet 0\): invalid toml: line 3`),
+			},
+		},
+	})
+}
@@ -0,0 +1,333 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func computedString() *schema.Schema {
+	return &schema.Schema{Type: schema.TypeString, Computed: true}
+}
+
+func computedInt() *schema.Schema {
+	return &schema.Schema{Type: schema.TypeInt, Computed: true}
+}
+
+func computedBool() *schema.Schema {
+	return &schema.Schema{Type: schema.TypeBool, Computed: true}
+}
+
+func computedStringList() *schema.Schema {
+	return &schema.Schema{Type: schema.TypeList, Computed: true, Elem: &schema.Schema{Type: schema.TypeString}}
+}
+
+func computedList(elem map[string]*schema.Schema) *schema.Schema {
+	return &schema.Schema{Type: schema.TypeList, Computed: true, Elem: &schema.Resource{Schema: elem}}
+}
+
+func nodeSchema(elem map[string]*schema.Schema) map[string]*schema.Schema {
+	elem["path"] = computedString()
+	elem["overwrite"] = computedBool()
+	elem["user_id"] = computedInt()
+	elem["user_name"] = computedString()
+	elem["group_id"] = computedInt()
+	elem["group_name"] = computedString()
+	return elem
+}
+
+func datasourceConfigInspect() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceConfigInspectRead,
+
+		Schema: map[string]*schema.Schema{
+			"content": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "rendered ignition configuration to inspect",
+			},
+			"version": computedString(),
+			"files": computedList(nodeSchema(map[string]*schema.Schema{
+				"mode":        computedString(),
+				"contents":    computedString(),
+				"source":      computedString(),
+				"compression": computedString(),
+				"hash":        computedString(),
+				"append":      computedStringList(),
+			})),
+			"directories": computedList(nodeSchema(map[string]*schema.Schema{
+				"mode": computedString(),
+			})),
+			"links": computedList(nodeSchema(map[string]*schema.Schema{
+				"target": computedString(),
+				"hard":   computedBool(),
+			})),
+			"units": computedList(map[string]*schema.Schema{
+				"name":     computedString(),
+				"enabled":  computedBool(),
+				"mask":     computedBool(),
+				"contents": computedString(),
+				"dropins": computedList(map[string]*schema.Schema{
+					"name":     computedString(),
+					"contents": computedString(),
+				}),
+			}),
+			"users": computedList(map[string]*schema.Schema{
+				"name":                computedString(),
+				"uid":                 computedInt(),
+				"groups":              computedStringList(),
+				"primary_group":       computedString(),
+				"home_dir":            computedString(),
+				"shell":               computedString(),
+				"system":              computedBool(),
+				"has_password_hash":   computedBool(),
+				"ssh_authorized_keys": computedStringList(),
+			}),
+			"groups": computedList(map[string]*schema.Schema{
+				"name":   computedString(),
+				"gid":    computedInt(),
+				"system": computedBool(),
+			}),
+			"disks": computedList(map[string]*schema.Schema{
+				"device":     computedString(),
+				"wipe_table": computedBool(),
+				"partitions": computedList(map[string]*schema.Schema{
+					"number":    computedInt(),
+					"label":     computedString(),
+					"start_mib": computedInt(),
+					"size_mib":  computedInt(),
+					"type_guid": computedString(),
+					"guid":      computedString(),
+					"resize":    computedBool(),
+				}),
+			}),
+			"filesystems": computedList(map[string]*schema.Schema{
+				"device":          computedString(),
+				"format":          computedString(),
+				"path":            computedString(),
+				"label":           computedString(),
+				"uuid":            computedString(),
+				"wipe_filesystem": computedBool(),
+				"mount_options":   computedStringList(),
+			}),
+			"kernel_arguments_should_exist":     computedStringList(),
+			"kernel_arguments_should_not_exist": computedStringList(),
+			"remote_sources": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "URLs Ignition fetches at boot time, they are never fetched by the provider",
+			},
+		},
+	}
+}
+
+func datasourceConfigInspectRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	content := d.Get("content").(string)
+	config, err := configutil.ParseRendered([]byte(content))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("content parse error: %v", err))
+	}
+
+	version, _, _ := ignition_util.GetConfigVersion([]byte(content))
+	attributes, err := inspectConfig(config, version.String())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	for key, value := range attributes {
+		if err := d.Set(key, value); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	d.SetId(hashcode(content))
+	return diags
+}
+
+// Flatten an Ignition config into the attributes of the ignition_config_inspect data source.
+// version is the spec version of the content, as config is translated to the newest one.
+func inspectConfig(config types_v3_4.Config, version string) (map[string]interface{}, error) {
+	files := make([]interface{}, 0, len(config.Storage.Files))
+	for _, file := range config.Storage.Files {
+		contents, err := inspectResource(file.Contents)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %v", file.Path, err)
+		}
+		appended := make([]interface{}, 0, len(file.Append))
+		for _, resource := range file.Append {
+			decoded, err := inspectResource(resource)
+			if err != nil {
+				return nil, fmt.Errorf("file %s: append: %v", file.Path, err)
+			}
+			appended = append(appended, decoded)
+		}
+		files = append(files, inspectNode(file.Node, map[string]interface{}{
+			"mode":        configutil.FormatMode(file.Mode),
+			"contents":    contents,
+			"source":      configutil.RemoteSource(file.Contents),
+			"compression": configutil.StringValue(file.Contents.Compression),
+			"hash":        configutil.StringValue(file.Contents.Verification.Hash),
+			"append":      appended,
+		}))
+	}
+
+	directories := make([]interface{}, 0, len(config.Storage.Directories))
+	for _, directory := range config.Storage.Directories {
+		directories = append(directories, inspectNode(directory.Node, map[string]interface{}{
+			"mode": configutil.FormatMode(directory.Mode),
+		}))
+	}
+
+	links := make([]interface{}, 0, len(config.Storage.Links))
+	for _, link := range config.Storage.Links {
+		links = append(links, inspectNode(link.Node, map[string]interface{}{
+			"target": configutil.StringValue(link.Target),
+			"hard":   configutil.BoolValue(link.Hard),
+		}))
+	}
+
+	units := make([]interface{}, 0, len(config.Systemd.Units))
+	for _, unit := range config.Systemd.Units {
+		dropins := make([]interface{}, 0, len(unit.Dropins))
+		for _, dropin := range unit.Dropins {
+			dropins = append(dropins, map[string]interface{}{
+				"name":     dropin.Name,
+				"contents": configutil.StringValue(dropin.Contents),
+			})
+		}
+		units = append(units, map[string]interface{}{
+			"name":     unit.Name,
+			"enabled":  configutil.BoolValue(unit.Enabled),
+			"mask":     configutil.BoolValue(unit.Mask),
+			"contents": configutil.StringValue(unit.Contents),
+			"dropins":  dropins,
+		})
+	}
+
+	users := make([]interface{}, 0, len(config.Passwd.Users))
+	for _, user := range config.Passwd.Users {
+		groups := make([]interface{}, 0, len(user.Groups))
+		for _, group := range user.Groups {
+			groups = append(groups, string(group))
+		}
+		keys := make([]interface{}, 0, len(user.SSHAuthorizedKeys))
+		for _, key := range user.SSHAuthorizedKeys {
+			keys = append(keys, string(key))
+		}
+		users = append(users, map[string]interface{}{
+			"name":                user.Name,
+			"uid":                 configutil.IntValue(user.UID),
+			"groups":              groups,
+			"primary_group":       configutil.StringValue(user.PrimaryGroup),
+			"home_dir":            configutil.StringValue(user.HomeDir),
+			"shell":               configutil.StringValue(user.Shell),
+			"system":              configutil.BoolValue(user.System),
+			"has_password_hash":   user.PasswordHash != nil && *user.PasswordHash != "",
+			"ssh_authorized_keys": keys,
+		})
+	}
+
+	groups := make([]interface{}, 0, len(config.Passwd.Groups))
+	for _, group := range config.Passwd.Groups {
+		groups = append(groups, map[string]interface{}{
+			"name":   group.Name,
+			"gid":    configutil.IntValue(group.Gid),
+			"system": configutil.BoolValue(group.System),
+		})
+	}
+
+	disks := make([]interface{}, 0, len(config.Storage.Disks))
+	for _, disk := range config.Storage.Disks {
+		partitions := make([]interface{}, 0, len(disk.Partitions))
+		for _, partition := range disk.Partitions {
+			partitions = append(partitions, map[string]interface{}{
+				"number":    partition.Number,
+				"label":     configutil.StringValue(partition.Label),
+				"start_mib": configutil.IntValue(partition.StartMiB),
+				"size_mib":  configutil.IntValue(partition.SizeMiB),
+				"type_guid": configutil.StringValue(partition.TypeGUID),
+				"guid":      configutil.StringValue(partition.GUID),
+				"resize":    configutil.BoolValue(partition.Resize),
+			})
+		}
+		disks = append(disks, map[string]interface{}{
+			"device":     disk.Device,
+			"wipe_table": configutil.BoolValue(disk.WipeTable),
+			"partitions": partitions,
+		})
+	}
+
+	filesystems := make([]interface{}, 0, len(config.Storage.Filesystems))
+	for _, filesystem := range config.Storage.Filesystems {
+		mountOptions := make([]interface{}, 0, len(filesystem.MountOptions))
+		for _, option := range filesystem.MountOptions {
+			mountOptions = append(mountOptions, string(option))
+		}
+		filesystems = append(filesystems, map[string]interface{}{
+			"device":          filesystem.Device,
+			"format":          configutil.StringValue(filesystem.Format),
+			"path":            configutil.StringValue(filesystem.Path),
+			"label":           configutil.StringValue(filesystem.Label),
+			"uuid":            configutil.StringValue(filesystem.UUID),
+			"wipe_filesystem": configutil.BoolValue(filesystem.WipeFilesystem),
+			"mount_options":   mountOptions,
+		})
+	}
+
+	shouldExist := make([]interface{}, 0, len(config.KernelArguments.ShouldExist))
+	for _, argument := range config.KernelArguments.ShouldExist {
+		shouldExist = append(shouldExist, string(argument))
+	}
+	shouldNotExist := make([]interface{}, 0, len(config.KernelArguments.ShouldNotExist))
+	for _, argument := range config.KernelArguments.ShouldNotExist {
+		shouldNotExist = append(shouldNotExist, string(argument))
+	}
+
+	remoteSources := make([]interface{}, 0)
+	for _, source := range configutil.RemoteSources(config) {
+		remoteSources = append(remoteSources, source)
+	}
+
+	return map[string]interface{}{
+		"version":                           version,
+		"files":                             files,
+		"directories":                       directories,
+		"links":                             links,
+		"units":                             units,
+		"users":                             users,
+		"groups":                            groups,
+		"disks":                             disks,
+		"filesystems":                       filesystems,
+		"kernel_arguments_should_exist":     shouldExist,
+		"kernel_arguments_should_not_exist": shouldNotExist,
+		"remote_sources":                    remoteSources,
+	}, nil
+}
+
+func inspectNode(node types_v3_4.Node, attributes map[string]interface{}) map[string]interface{} {
+	attributes["path"] = node.Path
+	attributes["overwrite"] = configutil.BoolValue(node.Overwrite)
+	attributes["user_id"] = configutil.IntValue(node.User.ID)
+	attributes["user_name"] = configutil.StringValue(node.User.Name)
+	attributes["group_id"] = configutil.IntValue(node.Group.ID)
+	attributes["group_name"] = configutil.StringValue(node.Group.Name)
+	return attributes
+}
+
+// Decoded text of an inline resource. Remote and binary contents are left empty.
+func inspectResource(resource types_v3_4.Resource) (string, error) {
+	contents, ok, err := configutil.DecodeResource(resource)
+	if err != nil || !ok || !utf8.Valid(contents) {
+		return "", err
+	}
+	return string(contents), nil
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const configInspectResource = `
+data "ignition_config" "fedora-coreos" {
+  strict = true
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key
+storage:
+  files:
+    - path: /etc/motd
+      mode: 0644
+      user:
+        name: core
+      contents:
+        inline: hello
+    - path: /etc/compressed
+      contents:
+        inline: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+    - path: /etc/remote
+      contents:
+        source: https://example.com/remote
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+      dropins:
+        - name: override.conf
+          contents: |
+            [Service]
+            Restart=always
+kernel_arguments:
+  should_exist:
+    - quiet
+EOT
+}
+
+data "ignition_config_inspect" "fedora-coreos" {
+  content = data.ignition_config.fedora-coreos.rendered
+}
+`
+
+const configInspectVersionResource = `
+data "ignition_config_inspect" "fedora-coreos" {
+  content = jsonencode({ ignition = { version = "3.2.0" } })
+}
+`
+
+func TestConfigInspect(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: configInspectResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "version", "3.4.0"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.#", "3"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.0.path", "/etc/motd"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.0.mode", "0644"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.0.user_name", "core"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.0.contents", "hello"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.1.compression", "gzip"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.1.contents", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.2.contents", ""),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "files.2.source", "https://example.com/remote"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "remote_sources.#", "1"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "remote_sources.0", "https://example.com/remote"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "units.0.name", "docker.service"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "units.0.enabled", "true"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "units.0.dropins.0.name", "override.conf"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "units.0.dropins.0.contents", "[Service]\nRestart=always\n"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "users.0.name", "core"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "users.0.ssh_authorized_keys.0", "key"),
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "kernel_arguments_should_exist.0", "quiet"),
+				),
+			},
+			{
+				Config: configInspectVersionResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_config_inspect.fedora-coreos", "version", "3.2.0"),
+				),
+			},
+		},
+	})
+}
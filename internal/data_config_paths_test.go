@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const pathRulesResource = `
+data "ignition_config" "paths" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /usr/bin/tool
+      contents:
+        inline: tool
+EOT
+}
+`
+
+const pathRulesErrorResource = `
+data "ignition_config" "paths" {
+  check_severity = "error"
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /usr/bin/tool
+      contents:
+        inline: tool
+EOT
+}
+`
+
+const pathRulesDisabledResource = `
+data "ignition_config" "paths" {
+  validate_paths = false
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /usr/bin/tool
+      contents:
+        inline: tool
+EOT
+}
+`
+
+func TestPathRules(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: pathRulesResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.paths", "rendered"),
+				),
+			},
+			{
+				Config:      pathRulesErrorResource,
+				ExpectError: regexp.MustCompile(`file /usr/bin/tool \(content\): /usr is read-only on fcos, use /usr/local/bin/tool instead`),
+			},
+			{
+				Config: pathRulesDisabledResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.paths", "rendered"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
+)
+
+// A node of an ignition_config_set.
+type configSetNode struct {
+	name     string
+	vars     map[string]string
+	labels   map[string]string
+	snippets []string
+}
+
+// A snippet applied to the nodes whose labels match all labels of the selector.
+type labeledSnippet struct {
+	selector map[string]string
+	content  string
+}
+
+func datasourceConfigSet() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceConfigSetRead,
+
+		Schema: renderOptionsSchema(map[string]*schema.Schema{
+			"content": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "butane config shared by all nodes",
+			},
+			"snippets": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "butane snippets shared by all nodes",
+			},
+			"template": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "render the inputs as Go templates with the name, vars and labels of each node",
+			},
+			"labeled_snippet": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "butane snippets for the nodes matching a label selector",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"selector": {
+							Type:        schema.TypeMap,
+							Required:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "labels a node must have to get the snippet",
+						},
+						"content": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "butane snippet",
+						},
+					},
+				},
+			},
+			"node": {
+				Type:        schema.TypeList,
+				Required:    true,
+				Description: "nodes to render a config for",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "name of the node, the key of rendered and digests",
+						},
+						"vars": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "values for the templates of the inputs, available as {{ .vars.<name> }}",
+						},
+						"labels": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "labels of the node, matched against the selectors of labeled_snippet",
+						},
+						"snippets": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "butane snippets of the node, merged last",
+						},
+					},
+				},
+			},
+			"rendered": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "rendered ignition configurations by node",
+			},
+			"digests": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "sha512 digests of the rendered configurations by node, as used by ignition.config.merge verification",
+			},
+		}),
+	}
+}
+
+func datasourceConfigSetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	options, err := expandRenderOptions(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	content := d.Get("content").(string)
+	shared := expandStringList(d.Get("snippets"))
+	var labeled []labeledSnippet
+	for _, v := range d.Get("labeled_snippet").([]interface{}) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		labeled = append(labeled, labeledSnippet{selector: expandStringMap(m["selector"]), content: m["content"].(string)})
+	}
+	var nodes []configSetNode
+	seen := map[string]bool{}
+	for _, v := range d.Get("node").([]interface{}) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		node := configSetNode{
+			name:     m["name"].(string),
+			vars:     expandStringMap(m["vars"]),
+			labels:   expandStringMap(m["labels"]),
+			snippets: expandStringList(m["snippets"]),
+		}
+		if seen[node.name] {
+			return diag.Errorf("node %s is defined more than once", node.name)
+		}
+		seen[node.name] = true
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+
+	// inputs without templates are the same for all nodes and transpiled once, see render.Render;
+	// inputs embedding local files are transpiled once per read
+	ctx = render.WithLocalFilesCache(ctx)
+	templated := d.Get("template").(bool)
+	rendered := map[string]interface{}{}
+	digests := map[string]interface{}{}
+	var diags diag.Diagnostics
+	start := time.Now()
+	for _, node := range nodes {
+		nodeCtx := tflog.SetField(ctx, "node", node.name)
+		result, nodeDiags := renderNode(nodeCtx, node, content, shared, labeled, templated, options)
+		for _, nodeDiag := range nodeDiags {
+			if nodeDiag.Detail != "" {
+				nodeDiag.Detail = fmt.Sprintf("node %s: %s", node.name, nodeDiag.Detail)
+			} else {
+				nodeDiag.Summary = fmt.Sprintf("node %s: %s", node.name, nodeDiag.Summary)
+			}
+			diags = append(diags, nodeDiag)
+		}
+		if nodeDiags.HasError() {
+			continue
+		}
+		sum := sha512.Sum512(result.Rendered)
+		rendered[node.name] = string(result.Rendered)
+		digests[node.name] = "sha512-" + hex.EncodeToString(sum[:])
+		tflog.Debug(nodeCtx, "rendered node config", map[string]interface{}{"rendered_bytes": len(result.Rendered)})
+	}
+	fields := diagnosticFields(diags)
+	fields["nodes"] = len(nodes)
+	fields["duration_ms"] = milliseconds(time.Since(start))
+	if diags.HasError() {
+		tflog.Debug(ctx, "failed to render ignition_config_set", fields)
+		return diags
+	}
+	tflog.Debug(ctx, "rendered ignition_config_set", fields)
+	if err := d.Set("rendered", rendered); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	if err := d.Set("digests", digests); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	var ids []string
+	for _, node := range nodes {
+		ids = append(ids, digests[node.name].(string))
+	}
+	d.SetId(hashcode(strings.Join(ids, "\n")))
+	return diags
+}
+
+// Render the config of a node from the shared inputs, the labeled snippets matching
+// its labels and its own snippets, in this order. The inputs are only executed as
+// templates if templated is set, as Butane configs may contain "{{" themselves.
+func renderNode(ctx context.Context, node configSetNode, content string, shared []string, labeled []labeledSnippet, templated bool, options render.Options) (render.Result, diag.Diagnostics) {
+	data := map[string]interface{}{
+		"name":   node.name,
+		"vars":   node.vars,
+		"labels": node.labels,
+	}
+	expand := func(name, input string) (string, error) {
+		if !templated {
+			return input, nil
+		}
+		return executeTemplate(name, input, data)
+	}
+	content, err := expand("content", content)
+	if err != nil {
+		return render.Result{}, diag.FromErr(err)
+	}
+	var snippets []string
+	add := func(name, snippet string) error {
+		snippet, err := expand(name, snippet)
+		snippets = append(snippets, snippet)
+		return err
+	}
+	for i, snippet := range shared {
+		if err := add(fmt.Sprintf("snippet %d", i), snippet); err != nil {
+			return render.Result{}, diag.FromErr(err)
+		}
+	}
+	for i, snippet := range labeled {
+		if !matchLabels(snippet.selector, node.labels) {
+			continue
+		}
+		if err := add(fmt.Sprintf("labeled_snippet %d", i), snippet.content); err != nil {
+			return render.Result{}, diag.FromErr(err)
+		}
+	}
+	for i, snippet := range node.snippets {
+		if err := add(fmt.Sprintf("node snippet %d", i), snippet); err != nil {
+			return render.Result{}, diag.FromErr(err)
+		}
+	}
+	options.Content = content
+	options.Snippets = snippets
+	return renderDiagnostics(render.Render(ctx, options))
+}
+
+// Execute the Go template of an input. Inputs without template actions are returned
+// unchanged, so they stay identical across nodes.
+func executeTemplate(name, input string, data map[string]interface{}) (string, error) {
+	if !strings.Contains(input, "{{") {
+		return input, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return b.String(), nil
+}
+
+func matchLabels(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func expandStringMap(v interface{}) map[string]string {
+	result := map[string]string{}
+	m, _ := v.(map[string]interface{})
+	for key, value := range m {
+		if s, ok := value.(string); ok {
+			result[key] = s
+		}
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
+)
+
+const configSetResource = `
+data "ignition_config_set" "fleet" {
+  template = true
+  content  = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/hostname
+      contents:
+        inline: "{{ .name }}"
+EOT
+
+  labeled_snippet {
+    selector = { role = "worker" }
+    content  = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/zone
+      contents:
+        inline: "{{ .vars.zone }}"
+EOT
+  }
+
+  node {
+    name   = "worker-1"
+    labels = { role = "worker" }
+    vars   = { zone = "eu-1" }
+  }
+  node {
+    name = "control-1"
+  }
+}
+`
+
+const configSetMissingVarResource = `
+data "ignition_config_set" "fleet" {
+  template = true
+  content  = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/zone
+      contents:
+        inline: "{{ .vars.zone }}"
+EOT
+  node {
+    name = "control-1"
+  }
+}
+`
+
+func TestConfigSet(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: configSetResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_config_set.fleet", "rendered.worker-1", regexp.MustCompile(`"source":"data:,worker-1".*"source":"data:,eu-1"`)),
+					r.TestMatchResourceAttr("data.ignition_config_set.fleet", "rendered.control-1", regexp.MustCompile(`"source":"data:,control-1"`)),
+					r.TestCheckResourceAttrWith("data.ignition_config_set.fleet", "rendered.control-1", func(value string) error {
+						if strings.Contains(value, "/etc/zone") {
+							return fmt.Errorf("labeled snippet rendered for a node without matching labels")
+						}
+						return nil
+					}),
+					r.TestMatchResourceAttr("data.ignition_config_set.fleet", "digests.control-1", regexp.MustCompile(`^sha512-[0-9a-f]{128}$`)),
+				),
+			},
+			{
+				Config: configSetNoTemplateResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_config_set.fleet", "rendered.control-1", regexp.MustCompile(`"source":"data:,%7B%7B%20.vars.zone%20%7D%7D"`)),
+				),
+			},
+			{
+				Config:      configSetMissingVarResource,
+				ExpectError: regexp.MustCompile(`node control-1: content: template: .*map has no entry for key "zone"`),
+			},
+		},
+	})
+}
+
+const configSetNoTemplateResource = `
+data "ignition_config_set" "fleet" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  files:
+    - path: /etc/zone
+      contents:
+        inline: "{{ .vars.zone }}"
+EOT
+  node {
+    name = "control-1"
+  }
+}
+`
+
+func TestRenderNodeWithoutTemplate(t *testing.T) {
+	content := "variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/format\n      contents:\n        inline: \"{{.ID}} {{ .name }}\"\n"
+	node := configSetNode{name: "node-1"}
+	result, diags := renderNode(context.Background(), node, content, []string{content}, nil, false, render.Options{})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	config, err := configutil.ParseRendered(result.Rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, _, err := configutil.DecodeResource(config.Storage.Files[0].Contents)
+	if err != nil || string(contents) != "{{.ID}} {{ .name }}" {
+		t.Errorf("expected the input unchanged, got %q, %v", contents, err)
+	}
+	if _, diags := renderNode(context.Background(), node, content, nil, nil, true, render.Options{}); !diags.HasError() {
+		t.Errorf("expected a template error")
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	data := map[string]interface{}{"name": "node-1", "vars": map[string]string{"zone": "eu-1"}}
+	// inputs without template actions are not parsed, so they may contain anything
+	if output, err := executeTemplate("content", "{ .name }} {{", data); err == nil || output != "" {
+		t.Errorf("expected a parse error, got %q", output)
+	}
+	if output, err := executeTemplate("content", "echo ${HOME} }}", data); err != nil || output != "echo ${HOME} }}" {
+		t.Errorf("expected the input unchanged, got %q, %v", output, err)
+	}
+	if output, err := executeTemplate("content", "{{ .name }} in {{ .vars.zone }}", data); err != nil || output != "node-1 in eu-1" {
+		t.Errorf("unexpected output %q, %v", output, err)
+	}
+	if _, err := executeTemplate("content", "{{ .vars.rack }}", data); err == nil || !strings.Contains(err.Error(), `no entry for key "rack"`) {
+		t.Errorf("expected an error for a missing var, got %v", err)
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"role": "worker", "zone": "eu-1"}
+	tests := []struct {
+		selector map[string]string
+		match    bool
+	}{
+		{map[string]string{}, true},
+		{map[string]string{"role": "worker"}, true},
+		{map[string]string{"role": "worker", "zone": "eu-1"}, true},
+		{map[string]string{"role": "control"}, false},
+		{map[string]string{"rack": ""}, true},
+		{map[string]string{"rack": "a"}, false},
+	}
+	for _, test := range tests {
+		if match := matchLabels(test.selector, labels); match != test.match {
+			t.Errorf("%v: expected %t, got %t", test.selector, test.match, match)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const storageLayoutResource = `
+data "ignition_config" "storage" {
+  disk_sizes = {
+    "/dev/vdb" = 10240
+  }
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  disks:
+    - device: /dev/vdb
+      wipe_table: true
+      partitions:
+        - label: data
+          size_mib: 4096
+        - label: log
+  filesystems:
+    - device: /dev/disk/by-partlabel/data
+      path: /var/data
+      format: xfs
+EOT
+}
+`
+
+const storageOverlapResource = `
+data "ignition_config" "storage" {
+  check_severity = "error"
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+storage:
+  disks:
+    - device: /dev/vdb
+      wipe_table: true
+      partitions:
+        - label: data
+          size_mib: 4096
+        - label: log
+          start_mib: 2048
+          size_mib: 1024
+EOT
+}
+`
+
+func TestStorageLayout(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: storageLayoutResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.#", "2"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.0.number", "1"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.0.start_mib", "1"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.0.size_mib", "4096"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.1.number", "2"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.1.start_mib", "4097"),
+					r.TestCheckResourceAttr("data.ignition_config.storage", "storage_layout.1.size_mib", "6142"),
+				),
+			},
+			{
+				Config:      storageOverlapResource,
+				ExpectError: regexp.MustCompile(`partition 2 \(log\) on /dev/vdb \(content\): overlaps with partition 1 \(data\) on /dev/vdb`),
+			},
+		},
+	})
+}
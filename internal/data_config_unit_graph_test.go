@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const unitGraphContent = `
+---
+variant: fcos
+version: 1.5.0
+systemd:
+  units:
+    - name: app.service
+      enabled: true
+      contents: |
+        [Unit]
+        Requires=db.service missing.service
+        After=db.service
+        [Service]
+        ExecStart=/usr/bin/app
+        [Install]
+        WantedBy=multi-user.target
+    - name: db.service
+      contents: |
+        [Unit]
+        After=app.service
+        [Service]
+        ExecStart=/usr/bin/db
+    - name: report.service
+      enabled: true
+      contents: |
+        [Service]
+        ExecStart=/usr/bin/report
+        [Install]
+        WantedBy=custom.target
+    - name: custom.target
+      contents: |
+        [Unit]
+        Description=Custom
+    - name: docker.service
+      enabled: true
+`
+
+const unitGraphResource = `
+data "ignition_config" "units" {
+  content = <<EOT` + unitGraphContent + `EOT
+}
+`
+
+func TestUnitGraph(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: unitGraphResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_config.units", "unit_graph", regexp.MustCompile(`"app.service" -> "db.service" \[label="Requires"\];`)),
+					r.TestMatchResourceAttr("data.ignition_config.units", "unit_graph", regexp.MustCompile(`"missing.service" \[color=red\];`)),
+				),
+			},
+		},
+	})
+}
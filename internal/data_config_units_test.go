@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const unitLintContent = `
+---
+variant: fcos
+version: 1.5.0
+systemd:
+  units:
+    - name: hello.service
+      enabled: true
+      contents: |
+        [Unit]
+        Description=Hello
+        After=network-online.target
+
+        [Servcie]
+        ExecStart=/usr/bin/echo hello
+    - name: backup.service
+      contents: |
+        [Unit]
+        Description=Backup
+        [Service]
+        Type=simple
+        ExecStartt=/usr/local/bin/backup
+    - name: cleanup.service
+      enabled: true
+      contents: |
+        [Service]
+        Type=oneshot
+        ExecStart=/usr/bin/true \
+          --verbose
+        [Install]
+        WantedBy=multi-user.target
+`
+
+const unitLintSnippet = `
+---
+variant: fcos
+version: 1.5.0
+systemd:
+  units:
+    - name: docker.service
+      dropins:
+        - name: 10-env.conf
+          contents: |
+            [Service]
+            Environment=A=1
+            Enviroment=B=2
+`
+
+const unitLintResource = `
+data "ignition_config" "units" {
+  content = <<EOT` + unitLintContent + `EOT
+  snippets = [
+<<EOT` + unitLintSnippet + `EOT
+  ]
+}
+`
+
+func TestUnitLint(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: unitLintResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.units", "rendered"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func datasourceDirectory() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceDirectoryRead,
+
+		Schema: nodeArguments(map[string]*schema.Schema{
+			"mode": modeArgument("directory permissions in decimal, e.g. 493 for 0755"),
+		}),
+	}
+}
+
+func datasourceDirectoryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	config := types_v3_4.Config{}
+	config.Storage.Directories = []types_v3_4.Directory{{
+		Node:               expandNode(d),
+		DirectoryEmbedded1: types_v3_4.DirectoryEmbedded1{Mode: expandMode(d)},
+	}}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const directoryResource = `
+data "ignition_directory" "data" {
+  path     = "/var/lib/data"
+  mode     = 448
+  group_id = 1000
+}
+`
+
+func TestDirectory(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: directoryResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_directory.data", "rendered", regexp.MustCompile(`(?s)directories:\n  - path: /var/lib/data\n.*mode: 448`)),
+				),
+			},
+			{
+				Config:      `data "ignition_directory" "data" { path = "/var/lib/../data" }`,
+				ExpectError: regexp.MustCompile(`path must be a clean path`),
+			},
+		},
+	})
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+var validateGUID = validation.StringMatch(regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), "must be a GUID")
+
+func datasourceDisk() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceDiskRead,
+
+		Schema: map[string]*schema.Schema{
+			"device": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "absolute path of the disk device",
+			},
+			"wipe_table": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to wipe the partition table before partitioning",
+			},
+			"partition": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "partitions of the disk",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"number": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntBetween(0, 128),
+							Description:  "partition number, 0 for the next free number",
+						},
+						"label": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringLenBetween(0, 36),
+							Description:  "partition label",
+						},
+						"start_mib": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+							Description:  "start of the partition in MiB, unset for the start of the largest free block",
+						},
+						"size_mib": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+							Description:  "size of the partition in MiB, unset to fill the free block",
+						},
+						"type_guid": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateGUID,
+							Description:  "partition type GUID",
+						},
+						"guid": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateGUID,
+							Description:  "unique partition GUID",
+						},
+						"wipe_partition_entry": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "whether to replace an existing partition which does not match",
+						},
+						"should_exist": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     true,
+							Description: "whether the partition should exist, false deletes it",
+						},
+						"resize": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "whether to grow an existing partition to size_mib",
+						},
+					},
+				},
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "butane snippet for the snippets of ignition_config",
+			},
+		},
+	}
+}
+
+func datasourceDiskRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	disk := types_v3_4.Disk{
+		Device:    d.Get("device").(string),
+		WipeTable: optionalBool(d, "wipe_table"),
+	}
+	for i, v := range d.Get("partition").([]interface{}) {
+		p, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		partition := types_v3_4.Partition{Number: p["number"].(int)}
+		// nested blocks cannot tell unset from zero, zero values are left unset
+		if label := p["label"].(string); label != "" {
+			partition.Label = &label
+		}
+		if start := p["start_mib"].(int); start != 0 {
+			partition.StartMiB = &start
+		}
+		if size := p["size_mib"].(int); size != 0 {
+			partition.SizeMiB = &size
+		}
+		if typeGUID := p["type_guid"].(string); typeGUID != "" {
+			partition.TypeGUID = &typeGUID
+		}
+		if guid := p["guid"].(string); guid != "" {
+			partition.GUID = &guid
+		}
+		if p["wipe_partition_entry"].(bool) {
+			wipe := true
+			partition.WipePartitionEntry = &wipe
+		}
+		if !p["should_exist"].(bool) {
+			shouldExist := false
+			partition.ShouldExist = &shouldExist
+		}
+		if p["resize"].(bool) {
+			resize := true
+			partition.Resize = &resize
+		}
+		if partition.Number == 0 && partition.Label == nil {
+			return diag.FromErr(fmt.Errorf("partition %d: a number or a label is required", i))
+		}
+		disk.Partitions = append(disk.Partitions, partition)
+	}
+
+	config := types_v3_4.Config{}
+	config.Storage.Disks = []types_v3_4.Disk{disk}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
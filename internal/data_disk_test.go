@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const diskResource = `
+data "ignition_disk" "root" {
+  device = "/dev/vda"
+  partition {
+    number   = 4
+    label    = "root"
+    size_mib = 8192
+    resize   = true
+  }
+  partition {
+    label = "data"
+  }
+}
+`
+
+func TestDisk(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: diskResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_disk.root", "rendered", regexp.MustCompile(`version: 1.3.0`)),
+					r.TestMatchResourceAttr("data.ignition_disk.root", "rendered", regexp.MustCompile(`- label: data\n`)),
+				),
+			},
+			{
+				Config:      `data "ignition_disk" "root" { device = "/dev/vda" partition { size_mib = 10 } }`,
+				ExpectError: regexp.MustCompile(`partition 0: a number or a label is required`),
+			},
+		},
+	})
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func resourceArguments(elem map[string]*schema.Schema) map[string]*schema.Schema {
+	elem["content"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "inline contents",
+	}
+	elem["source"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "URL of the contents",
+	}
+	elem["compression"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validation.StringInSlice([]string{"gzip"}, false),
+		Description:  "compression of the source",
+	}
+	elem["verification"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "hash of the uncompressed contents, as sha512-<hex> or sha256-<hex>",
+	}
+	return elem
+}
+
+func datasourceFile() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceFileRead,
+
+		Schema: nodeArguments(resourceArguments(map[string]*schema.Schema{
+			"mode": modeArgument("file permissions in decimal, e.g. 420 for 0644"),
+			"append": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Resource{Schema: resourceArguments(map[string]*schema.Schema{})},
+				Description: "contents to append to the file",
+			},
+		})),
+	}
+}
+
+func datasourceFileRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	contents, err := expandResource(
+		d.Get("content").(string),
+		d.Get("source").(string),
+		d.Get("compression").(string),
+		d.Get("verification").(string),
+	)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	file := types_v3_4.File{
+		Node: expandNode(d),
+		FileEmbedded1: types_v3_4.FileEmbedded1{
+			Contents: contents,
+			Mode:     expandMode(d),
+		},
+	}
+	for i, v := range d.Get("append").([]interface{}) {
+		appended, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resource, err := expandResource(
+			appended["content"].(string),
+			appended["source"].(string),
+			appended["compression"].(string),
+			appended["verification"].(string),
+		)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("append %d: %v", i, err))
+		}
+		file.Append = append(file.Append, resource)
+	}
+
+	config := types_v3_4.Config{}
+	config.Storage.Files = []types_v3_4.File{file}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const fileResource = `
+data "ignition_file" "motd" {
+  path      = "/etc/motd"
+  mode      = 420
+  user_name = "core"
+  content   = "hello\n"
+  append {
+    source      = "https://example.com/motd.gz"
+    compression = "gzip"
+  }
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [data.ignition_file.motd.rendered]
+}
+`
+
+const fileInvalidResource = `
+data "ignition_file" "motd" {
+  path        = "/etc/motd"
+  content     = "hello\n"
+  compression = "gzip"
+}
+`
+
+func TestFile(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: fileResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_file.motd", "rendered", regexp.MustCompile(`inline: \|\n +hello\n`)),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`"path":"/etc/motd".*"mode":420`)),
+				),
+			},
+			{
+				Config:      fileInvalidResource,
+				ExpectError: regexp.MustCompile(`compression requires a source, inline content is compressed automatically`),
+			},
+		},
+	})
+}
+
+func TestExpandResource(t *testing.T) {
+	hello := "sha512-" + "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"
+	tests := []struct {
+		content, source, compression, verification string
+		err                                        string
+	}{
+		{content: "hello\n", verification: hello},
+		{source: "s3://bucket/key", compression: "gzip"},
+		{content: "hello", source: "https://example.com", err: "mutually exclusive"},
+		{content: "hello", compression: "gzip", err: "compression requires a source"},
+		{source: "ftp://example.com/file", err: "the scheme must be one of"},
+		{content: "hello\n", verification: "md5-abc", err: "verification must be"},
+		{content: "bye\n", verification: hello, err: "does not match the content"},
+		{verification: hello, err: "requires content or a source"},
+	}
+	for _, test := range tests {
+		_, err := expandResource(test.content, test.source, test.compression, test.verification)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%+v: unexpected error %v", test, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%+v: expected error %q, got %v", test, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func datasourceFilesystem() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceFilesystemRead,
+
+		Schema: map[string]*schema.Schema{
+			"device": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "absolute path of the device",
+			},
+			"format": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"ext4", "btrfs", "xfs", "vfat", "swap", "none"}, false),
+				Description:  "filesystem type",
+			},
+			"path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "mount point of the filesystem",
+			},
+			"label": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "filesystem label",
+			},
+			"uuid": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "filesystem UUID",
+			},
+			"options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "additional options for mkfs",
+			},
+			"mount_options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "options for mounting the filesystem",
+			},
+			"wipe_filesystem": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to replace an existing filesystem which does not match",
+			},
+			"with_mount_unit": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "whether to add a mount or swap unit for the filesystem",
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "butane snippet for the snippets of ignition_config",
+			},
+		},
+	}
+}
+
+func datasourceFilesystemRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	format := d.Get("format").(string)
+	filesystem := types_v3_4.Filesystem{
+		Device:         d.Get("device").(string),
+		Format:         &format,
+		Path:           optionalString(d, "path"),
+		Label:          optionalString(d, "label"),
+		UUID:           optionalString(d, "uuid"),
+		WipeFilesystem: optionalBool(d, "wipe_filesystem"),
+	}
+	for _, option := range d.Get("options").([]interface{}) {
+		value, _ := option.(string)
+		filesystem.Options = append(filesystem.Options, types_v3_4.FilesystemOption(value))
+	}
+	for _, option := range d.Get("mount_options").([]interface{}) {
+		value, _ := option.(string)
+		filesystem.MountOptions = append(filesystem.MountOptions, types_v3_4.MountOption(value))
+	}
+
+	config := types_v3_4.Config{}
+	config.Storage.Filesystems = []types_v3_4.Filesystem{filesystem}
+	if d.Get("with_mount_unit").(bool) {
+		unit, err := mountUnit(filesystem)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		config.Systemd.Units = []types_v3_4.Unit{unit}
+	}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
+
+// The mount or swap unit of a filesystem, as Butane creates for with_mount_unit.
+// Filesystems with the _netdev mount option are mounted once the network is up.
+func mountUnit(filesystem types_v3_4.Filesystem) (types_v3_4.Unit, error) {
+	format := configutil.StringValue(filesystem.Format)
+	var options []string
+	remote := false
+	for _, option := range filesystem.MountOptions {
+		options = append(options, string(option))
+		remote = remote || option == "_netdev"
+	}
+	var contents strings.Builder
+	contents.WriteString("# Generated by Terraform\n")
+	enabled := true
+	if format == "swap" {
+		contents.WriteString("[Swap]\n")
+		fmt.Fprintf(&contents, "What=%s\n", filesystem.Device)
+		if len(options) > 0 {
+			fmt.Fprintf(&contents, "Options=%s\n", strings.Join(options, ","))
+		}
+		contents.WriteString("\n[Install]\nRequiredBy=swap.target\n")
+		unit := contents.String()
+		return types_v3_4.Unit{Name: escapeUnitPath(filesystem.Device) + ".swap", Enabled: &enabled, Contents: &unit}, nil
+	}
+	if format == "none" {
+		return types_v3_4.Unit{}, fmt.Errorf("with_mount_unit requires a filesystem format other than none")
+	}
+	if filesystem.Path == nil {
+		return types_v3_4.Unit{}, fmt.Errorf("with_mount_unit requires a path")
+	}
+	target := "local-fs.target"
+	contents.WriteString("[Unit]\n")
+	if remote {
+		target = "remote-fs.target"
+		contents.WriteString("DefaultDependencies=no\n")
+	}
+	fsck := fmt.Sprintf("systemd-fsck@%s.service", escapeUnitPath(filesystem.Device))
+	fmt.Fprintf(&contents, "Before=%s\nRequires=%s\nAfter=%s\n\n", target, fsck, fsck)
+	fmt.Fprintf(&contents, "[Mount]\nWhere=%s\nWhat=%s\nType=%s\n", *filesystem.Path, filesystem.Device, format)
+	if len(options) > 0 {
+		fmt.Fprintf(&contents, "Options=%s\n", strings.Join(options, ","))
+	}
+	fmt.Fprintf(&contents, "\n[Install]\nRequiredBy=%s\n", target)
+	unit := contents.String()
+	return types_v3_4.Unit{Name: escapeUnitPath(*filesystem.Path) + ".mount", Enabled: &enabled, Contents: &unit}, nil
+}
+
+// Escape a path for a unit name like systemd-escape --path does.
+func escapeUnitPath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return "-"
+	}
+	var escaped strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '/':
+			// duplicate slashes are collapsed
+			if p[i-1] != '/' {
+				escaped.WriteByte('-')
+			}
+		case c == '.' && i == 0,
+			!(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ':' || c == '_' || c == '.'):
+			fmt.Fprintf(&escaped, `\x%02x`, c)
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const filesystemResource = `
+data "ignition_filesystem" "data" {
+  device          = "/dev/disk/by-partlabel/data"
+  format          = "xfs"
+  path            = "/var/lib/data"
+  label           = "data"
+  mount_options   = ["noatime"]
+  with_mount_unit = true
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [data.ignition_filesystem.data.rendered]
+}
+`
+
+const filesystemOldVersionResource = `
+data "ignition_filesystem" "data" {
+  device        = "/dev/disk/by-partlabel/data"
+  format        = "xfs"
+  mount_options = ["noatime"]
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.0.0
+EOT
+  snippets = [data.ignition_filesystem.data.rendered]
+}
+`
+
+func TestFilesystem(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: filesystemResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_filesystem.data", "rendered", regexp.MustCompile(`version: 1.1.0`)),
+					r.TestMatchResourceAttr("data.ignition_filesystem.data", "rendered", regexp.MustCompile(`- name: var-lib-data.mount`)),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`Requires=systemd-fsck@dev-disk-by\\\\x2dpartlabel-data.service`)),
+				),
+			},
+			{
+				Config:      filesystemOldVersionResource,
+				ExpectError: regexp.MustCompile(`snippet parse error`),
+			},
+		},
+	})
+}
+
+func TestEscapeUnitPath(t *testing.T) {
+	// expected values from systemd-escape --path
+	tests := map[string]string{
+		"/var/lib/my-data//x/":   `var-lib-my\x2ddata-x`,
+		"/dev/disk/by-label/var": `dev-disk-by\x2dlabel-var`,
+		"/":                      "-",
+		"/.hidden/a b":           `\x2ehidden-a\x20b`,
+	}
+	for p, expected := range tests {
+		if escaped := escapeUnitPath(p); escaped != expected {
+			t.Errorf("%s: expected %s, got %s", p, expected, escaped)
+		}
+	}
+}
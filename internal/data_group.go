@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func datasourceGroup() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceGroupRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAccountName,
+				Description:  "group name",
+			},
+			"gid": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "group ID, allocated by groupadd if not set",
+			},
+			"password_hash": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "crypt hash of the group password",
+			},
+			"system": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to create a system group",
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "butane snippet for the snippets of ignition_config, sensitive as it may contain the password hash",
+			},
+		},
+	}
+}
+
+func datasourceGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	group := types_v3_4.PasswdGroup{Name: d.Get("name").(string)}
+	if gid, ok := d.GetOkExists("gid"); ok {
+		value := gid.(int)
+		group.Gid = &value
+	}
+	diags := passwordHashDiagnostics(d.Get("password_hash").(string))
+	if diags.HasError() {
+		return diags
+	}
+	group.PasswordHash = optionalString(d, "password_hash")
+	group.System = optionalBool(d, "system")
+
+	config := types_v3_4.Config{}
+	config.Passwd.Groups = []types_v3_4.PasswdGroup{group}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	d.SetId(hashcode(rendered))
+	return diags
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const groupResource = `
+data "ignition_group" "admins" {
+  name          = "admins"
+  gid           = 1500
+  password_hash = "!"
+  system        = true
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [
+    data.ignition_group.admins.rendered,
+  ]
+}
+`
+
+const groupInvalidNameResource = `
+data "ignition_group" "admins" {
+  name = "Admins"
+}
+`
+
+const groupInvalidPasswordHashResource = `
+data "ignition_group" "admins" {
+  name          = "admins"
+  password_hash = "hunter2"
+}
+`
+
+func TestGroup(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: groupResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_group.admins", "rendered", "version: 1.0.0\nvariant: fcos\npasswd:\n  groups:\n    - name: admins\n      gid: 1500\n      password_hash: '!'\n      system: true\n"),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`"groups":\[\{"gid":1500,"name":"admins","passwordHash":"!","system":true\}\]`)),
+				),
+			},
+			{
+				Config:      groupInvalidNameResource,
+				ExpectError: regexp.MustCompile(`must be a lower case account name`),
+			},
+			{
+				Config:      groupInvalidPasswordHashResource,
+				ExpectError: regexp.MustCompile(`password_hash is not a yescrypt, sha512crypt, sha256crypt or bcrypt hash`),
+			},
+		},
+	})
+}
+
+func TestPasswordHashDiagnostics(t *testing.T) {
+	for hash, expected := range map[string]string{
+		"":                                   "",
+		"!":                                  "",
+		"hunter2":                            "invalid password hash",
+		"$1$saltsalt$qjXMvbEw8oaL.CzflDugX/": "weak password hash",
+		"$6$saltsalt$qFmFH.bQmmtXzyBY0s9v7Oicd2z4XSIecDzlB5KiA2/jctKu9YterLp8wwnSq.qc.eoxqOmSuNp2xS0ktL3nh/": "",
+	} {
+		diags := passwordHashDiagnostics(hash)
+		if expected == "" && len(diags) != 0 || expected != "" && (len(diags) != 1 || diags[0].Summary != expected) {
+			t.Errorf("%q: expected %q, got %v", hash, expected, diags)
+		}
+	}
+}
+
+func TestSensitivePasswordHash(t *testing.T) {
+	for name, resource := range map[string]*schema.Resource{"ignition_user": datasourceUser(), "ignition_group": datasourceGroup()} {
+		if !resource.Schema["password_hash"].Sensitive || !resource.Schema["rendered"].Sensitive {
+			t.Errorf("%s: expected password_hash and rendered to be sensitive", name)
+		}
+	}
+}
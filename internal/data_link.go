@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func datasourceLink() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceLinkRead,
+
+		Schema: nodeArguments(map[string]*schema.Schema{
+			"target": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "target of the link",
+			},
+			"hard": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "create a hard link instead of a symbolic link",
+			},
+		}),
+	}
+}
+
+func datasourceLinkRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	target := d.Get("target").(string)
+	link := types_v3_4.Link{
+		Node:          expandNode(d),
+		LinkEmbedded1: types_v3_4.LinkEmbedded1{Target: &target},
+	}
+	if d.Get("hard").(bool) {
+		hard := true
+		link.Hard = &hard
+	}
+	config := types_v3_4.Config{}
+	config.Storage.Links = []types_v3_4.Link{link}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
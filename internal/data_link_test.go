@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const linkResource = `
+data "ignition_link" "timezone" {
+  path   = "/etc/localtime"
+  target = "../usr/share/zoneinfo/UTC"
+}
+`
+
+func TestLink(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: linkResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_link.timezone", "rendered", regexp.MustCompile(`target: ../usr/share/zoneinfo/UTC`)),
+				),
+			},
+		},
+	})
+}
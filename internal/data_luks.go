@@ -0,0 +1,281 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func datasourceLuks() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceLuksRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "name of the volume, available as /dev/mapper/<name>",
+			},
+			"device": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "absolute path of the device to encrypt",
+			},
+			"label": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringLenBetween(0, 47),
+				Description:  "label of the LUKS header",
+			},
+			"uuid": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateGUID,
+				Description:  "UUID of the LUKS header",
+			},
+			"key_file": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem:        &schema.Resource{Schema: luksKeyFileArguments()},
+				Description: "key of the volume, a random key is stored in /etc/luks if not set",
+			},
+			"options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "additional options for cryptsetup luksFormat",
+			},
+			"open_options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "additional options for cryptsetup luksOpen",
+			},
+			"discard": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to issue discard requests to the device",
+			},
+			"wipe_volume": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to replace an existing volume which does not match",
+			},
+			"clevis": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "clevis bindings to unlock the volume at boot",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"tpm2": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "whether to bind to the TPM2",
+						},
+						"tang": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "tang servers to bind to",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"url": {
+										Type:         schema.TypeString,
+										Required:     true,
+										ValidateFunc: validation.IsURLWithHTTPorHTTPS,
+										Description:  "URL of the tang server",
+									},
+									"thumbprint": {
+										Type:        schema.TypeString,
+										Required:    true,
+										Description: "thumbprint of the signing key of the server",
+									},
+									"advertisement": {
+										Type:        schema.TypeString,
+										Optional:    true,
+										Description: "advertisement of the server, to bind without fetching it",
+									},
+								},
+							},
+						},
+						"threshold": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(1),
+							Description:  "number of bindings required to unlock the volume",
+						},
+						"custom": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							MaxItems:    1,
+							Description: "custom clevis binding, conflicts with tpm2, tang and threshold",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"pin": {
+										Type:         schema.TypeString,
+										Required:     true,
+										ValidateFunc: validation.StringInSlice([]string{"tpm2", "tang", "sss"}, false),
+										Description:  "clevis pin",
+									},
+									"config": {
+										Type:         schema.TypeString,
+										Required:     true,
+										ValidateFunc: validation.StringIsJSON,
+										Description:  "JSON config of the pin",
+									},
+									"needs_network": {
+										Type:        schema.TypeBool,
+										Optional:    true,
+										Default:     false,
+										Description: "whether the binding requires the network",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "butane snippet for the snippets of ignition_config, sensitive as it may contain the key",
+			},
+		},
+	}
+}
+
+// Arguments of key_file. Inline content is the key itself, so it is sensitive.
+func luksKeyFileArguments() map[string]*schema.Schema {
+	elem := resourceArguments(map[string]*schema.Schema{})
+	elem["content"].Sensitive = true
+	return elem
+}
+
+func datasourceLuksRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	name := d.Get("name").(string)
+	if name == "" || strings.Contains(name, "/") {
+		return diag.Errorf("name must not be empty or contain /, got %q", name)
+	}
+	device := d.Get("device").(string)
+	luks := types_v3_4.Luks{
+		Name:       name,
+		Device:     &device,
+		Label:      optionalString(d, "label"),
+		UUID:       optionalString(d, "uuid"),
+		Discard:    optionalBool(d, "discard"),
+		WipeVolume: optionalBool(d, "wipe_volume"),
+	}
+	for _, option := range d.Get("options").([]interface{}) {
+		value, _ := option.(string)
+		luks.Options = append(luks.Options, types_v3_4.LuksOption(value))
+	}
+	for _, option := range d.Get("open_options").([]interface{}) {
+		value, _ := option.(string)
+		luks.OpenOptions = append(luks.OpenOptions, types_v3_4.OpenOption(value))
+	}
+	if keyFile, ok := firstElement(d.Get("key_file")); ok {
+		resource, err := expandResource(
+			keyFile["content"].(string),
+			keyFile["source"].(string),
+			keyFile["compression"].(string),
+			keyFile["verification"].(string),
+		)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("key_file: %v", err))
+		}
+		luks.KeyFile = resource
+	}
+	if clevis, ok := firstElement(d.Get("clevis")); ok {
+		expanded, err := expandClevis(clevis)
+		if err != nil {
+			return diag.FromErr(fmt.Errorf("clevis: %v", err))
+		}
+		luks.Clevis = expanded
+	}
+
+	config := types_v3_4.Config{}
+	config.Storage.Luks = []types_v3_4.Luks{luks}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
+
+func expandClevis(clevis map[string]interface{}) (types_v3_4.Clevis, error) {
+	var result types_v3_4.Clevis
+	bindings := 0
+	if clevis["tpm2"].(bool) {
+		tpm2 := true
+		result.Tpm2 = &tpm2
+		bindings++
+	}
+	for i, v := range clevis["tang"].([]interface{}) {
+		server, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		thumbprint := server["thumbprint"].(string)
+		advertisement := server["advertisement"].(string)
+		if err := validateTang(thumbprint, advertisement); err != nil {
+			return result, fmt.Errorf("tang %d: %v", i, err)
+		}
+		tang := types_v3_4.Tang{URL: server["url"].(string), Thumbprint: &thumbprint}
+		if advertisement != "" {
+			tang.Advertisement = &advertisement
+		}
+		result.Tang = append(result.Tang, tang)
+		bindings++
+	}
+	if threshold := clevis["threshold"].(int); threshold != 0 {
+		if threshold > bindings {
+			return result, fmt.Errorf("threshold %d exceeds the %d bindings of tpm2 and tang", threshold, bindings)
+		}
+		result.Threshold = &threshold
+	}
+	if custom, ok := firstElement(clevis["custom"]); ok {
+		if bindings > 0 || result.Threshold != nil {
+			return result, fmt.Errorf("custom conflicts with tpm2, tang and threshold")
+		}
+		pin, config := custom["pin"].(string), custom["config"].(string)
+		if !json.Valid([]byte(config)) {
+			return result, fmt.Errorf("custom: config is not valid JSON")
+		}
+		result.Custom.Pin = &pin
+		result.Custom.Config = &config
+		if custom["needs_network"].(bool) {
+			needsNetwork := true
+			result.Custom.NeedsNetwork = &needsNetwork
+		}
+		bindings++
+	}
+	if bindings == 0 {
+		return result, fmt.Errorf("at least one of tpm2, tang and custom is required")
+	}
+	return result, nil
+}
+
+// First element of a nested block with MaxItems 1.
+func firstElement(v interface{}) (map[string]interface{}, bool) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+	element, ok := list[0].(map[string]interface{})
+	return element, ok
+}
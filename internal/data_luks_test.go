@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const luksResource = `
+data "ignition_luks" "data" {
+  name    = "data"
+  device  = "/dev/disk/by-partlabel/data"
+  discard = true
+  clevis {
+    tpm2 = true
+    tang {
+      url        = "https://tang.example.com"
+      thumbprint = "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+    }
+    threshold = 2
+  }
+}
+`
+
+const luksKeyFileResource = `
+data "ignition_luks" "data" {
+  name   = "data"
+  device = "/dev/disk/by-partlabel/data"
+  key_file {
+    content = "secret key"
+  }
+}
+`
+
+const luksThresholdResource = `
+data "ignition_luks" "data" {
+  name   = "data"
+  device = "/dev/disk/by-partlabel/data"
+  clevis {
+    tpm2      = true
+    threshold = 2
+  }
+}
+`
+
+func TestLuks(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: luksResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_luks.data", "rendered", regexp.MustCompile(`version: 1.5.0`)),
+					r.TestMatchResourceAttr("data.ignition_luks.data", "rendered", regexp.MustCompile(`thumbprint: NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs`)),
+				),
+			},
+			{
+				Config: luksKeyFileResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_luks.data", "rendered", regexp.MustCompile(`inline: secret key`)),
+				),
+			},
+			{
+				Config:      luksThresholdResource,
+				ExpectError: regexp.MustCompile(`threshold 2 exceeds the 1 bindings`),
+			},
+		},
+	})
+}
+
+func TestLuksSensitiveKey(t *testing.T) {
+	luks := datasourceLuks()
+	keyFile := luks.Schema["key_file"].Elem.(*schema.Resource)
+	if !keyFile.Schema["content"].Sensitive || !luks.Schema["rendered"].Sensitive {
+		t.Errorf("expected the inline key and the rendered snippet to be sensitive")
+	}
+	if keyFile.Schema["source"].Sensitive {
+		t.Errorf("expected the source of the key not to be sensitive")
+	}
+}
+
+func TestValidateTang(t *testing.T) {
+	// RSA key and thumbprint of RFC 7638, section 3.1
+	key := `{"kty":"RSA","alg":"RS256","key_ops":["verify"],"e":"AQAB","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"}`
+	thumbprint := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+	advertisement := `{"payload":"` + base64.RawURLEncoding.EncodeToString([]byte(`{"keys":[`+key+`]}`)) + `","protected":"e30","signature":"c2ln"}`
+	exchangeOnly := strings.Replace(advertisement, base64.RawURLEncoding.EncodeToString([]byte(`{"keys":[`+key+`]}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"keys":[`+strings.Replace(key, `"verify"`, `"deriveKey"`, 1)+`]}`)), 1)
+
+	tests := []struct {
+		thumbprint, advertisement string
+		err                       string
+	}{
+		{thumbprint: thumbprint},
+		{thumbprint: thumbprint, advertisement: advertisement},
+		{thumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9X", err: "invalid thumbprint"},
+		{thumbprint: strings.Replace(thumbprint, "Nzb", "Mzb", 1), advertisement: advertisement, err: "does not match a signing key"},
+		{thumbprint: thumbprint, advertisement: exchangeOnly, err: "does not match a signing key"},
+		{thumbprint: thumbprint, advertisement: `{"keys":[]}`, err: "not a JWS"},
+	}
+	for _, test := range tests {
+		err := validateTang(test.thumbprint, test.advertisement)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", test.thumbprint, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: expected error %q, got %v", test.thumbprint, test.err, err)
+		}
+	}
+}
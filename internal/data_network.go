@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/vincent-petithory/dataurl"
+)
+
+// Network link names are interface names of at most 15 characters.
+var validateInterfaceName = validation.StringMatch(regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,15}$`), "must be an interface name of at most 15 characters")
+
+// Addressing arguments shared by interfaces, bonds, bridges and VLANs.
+func networkLinkArguments(elem map[string]*schema.Schema) *schema.Resource {
+	elem["name"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Required:     true,
+		ValidateFunc: validateInterfaceName,
+		Description:  "interface name",
+	}
+	elem["master"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Optional:    true,
+		Description: "name of the bond or bridge the link is a member of",
+	}
+	elem["dhcp"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      dhcpNone,
+		ValidateFunc: validation.StringInSlice([]string{dhcpNone, dhcpIPv4, dhcpIPv6, dhcpBoth}, false),
+		Description:  "address families to configure with DHCP and IPv6 autoconfiguration",
+	}
+	elem["addresses"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "static addresses with prefix length, e.g. 10.0.0.5/24",
+	}
+	elem["gateways"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "default gateways, at most one per address family",
+	}
+	elem["route"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Description: "static routes",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"destination": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "destination network, e.g. 10.1.0.0/16",
+				},
+				"gateway": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "gateway of the route",
+				},
+				"metric": {
+					Type:         schema.TypeInt,
+					Optional:     true,
+					ValidateFunc: validation.IntAtLeast(0),
+					Description:  "metric of the route",
+				},
+			},
+		},
+	}
+	elem["dns"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "DNS servers",
+	}
+	elem["dns_search"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "DNS search domains",
+	}
+	elem["mtu"] = &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		ValidateFunc: validation.IntBetween(68, 65535),
+		Description:  "MTU of the link",
+	}
+	return &schema.Resource{Schema: elem}
+}
+
+func datasourceNetwork() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceNetworkRead,
+
+		Schema: map[string]*schema.Schema{
+			"variant": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"fcos", "flatcar"}, false),
+				Description:  "variant to render for, NetworkManager keyfiles for fcos and systemd-networkd files for flatcar",
+			},
+			"hostname": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "hostname written to /etc/hostname",
+			},
+			"interface": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "physical interfaces",
+				Elem: networkLinkArguments(map[string]*schema.Schema{
+					"mac_address": {
+						Type:         schema.TypeString,
+						Optional:     true,
+						ValidateFunc: validation.IsMACAddress,
+						Description:  "MAC address to match the interface by instead of its name",
+					},
+				}),
+			},
+			"bond": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "bonds of interfaces",
+				Elem: networkLinkArguments(map[string]*schema.Schema{
+					"mode": {
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(bondModes, false),
+						Description:  "bonding mode",
+					},
+					"options": {
+						Type:        schema.TypeMap,
+						Optional:    true,
+						Elem:        &schema.Schema{Type: schema.TypeString},
+						Description: "bonding options by their kernel names, e.g. miimon",
+					},
+				}),
+			},
+			"bridge": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "bridges of interfaces",
+				Elem: networkLinkArguments(map[string]*schema.Schema{
+					"stp": {
+						Type:        schema.TypeBool,
+						Optional:    true,
+						Default:     false,
+						Description: "whether to enable the spanning tree protocol",
+					},
+				}),
+			},
+			"vlan": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Description: "VLANs",
+				Elem: networkLinkArguments(map[string]*schema.Schema{
+					"id": {
+						Type:         schema.TypeInt,
+						Required:     true,
+						ValidateFunc: validation.IntBetween(1, 4094),
+						Description:  "VLAN ID",
+					},
+					"parent": {
+						Type:        schema.TypeString,
+						Required:    true,
+						Description: "name of the interface, bond or bridge the VLAN is on",
+					},
+				}),
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "butane snippet for the snippets of ignition_config",
+			},
+		},
+	}
+}
+
+func datasourceNetworkRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var links []networkLink
+	for _, kind := range []string{"interface", "bond", "bridge", "vlan"} {
+		for _, v := range d.Get(kind).([]interface{}) {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			links = append(links, expandNetworkLink(kind, m))
+		}
+	}
+	if err := validateNetwork(links); err != nil {
+		return diag.FromErr(err)
+	}
+
+	var files []networkFile
+	if hostname := d.Get("hostname").(string); hostname != "" {
+		files = append(files, networkFile{path: "/etc/hostname", mode: 0644, contents: hostname + "\n"})
+	}
+	if d.Get("variant").(string) == "flatcar" {
+		files = append(files, networkdFiles(links)...)
+	} else {
+		files = append(files, networkManagerFiles(links)...)
+	}
+
+	config := types_v3_4.Config{}
+	for _, file := range files {
+		source := dataurl.EncodeBytes([]byte(file.contents))
+		mode := file.mode
+		config.Storage.Files = append(config.Storage.Files, types_v3_4.File{
+			Node: types_v3_4.Node{Path: file.path},
+			FileEmbedded1: types_v3_4.FileEmbedded1{
+				Contents: types_v3_4.Resource{Source: &source},
+				Mode:     &mode,
+			},
+		})
+	}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
+
+func expandNetworkLink(kind string, m map[string]interface{}) networkLink {
+	link := networkLink{
+		kind:      kind,
+		name:      m["name"].(string),
+		master:    m["master"].(string),
+		dhcp:      m["dhcp"].(string),
+		addresses: expandStringList(m["addresses"]),
+		gateways:  expandStringList(m["gateways"]),
+		dns:       expandStringList(m["dns"]),
+		dnsSearch: expandStringList(m["dns_search"]),
+		mtu:       m["mtu"].(int),
+	}
+	for _, v := range m["route"].([]interface{}) {
+		route, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		link.routes = append(link.routes, networkRoute{
+			destination: route["destination"].(string),
+			gateway:     route["gateway"].(string),
+			metric:      route["metric"].(int),
+		})
+	}
+	switch kind {
+	case "interface":
+		link.kind = "ethernet"
+		link.macAddress = m["mac_address"].(string)
+	case "bond":
+		link.bondMode = m["mode"].(string)
+		link.bondOptions = map[string]string{}
+		for key, value := range m["options"].(map[string]interface{}) {
+			link.bondOptions[key] = fmt.Sprint(value)
+		}
+	case "bridge":
+		link.stp = m["stp"].(bool)
+	case "vlan":
+		link.vlanID = m["id"].(int)
+		link.parent = m["parent"].(string)
+	}
+	return link
+}
+
+func expandStringList(v interface{}) []string {
+	var result []string
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
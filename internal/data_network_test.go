@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const networkResource = `
+data "ignition_network" "fcos" {
+  variant  = "fcos"
+  hostname = "node1"
+  interface {
+    name      = "eno1"
+    addresses = ["10.0.0.5/24"]
+    gateways  = ["10.0.0.1"]
+    dns       = ["10.0.0.2"]
+  }
+}
+
+data "ignition_network" "flatcar" {
+  variant = "flatcar"
+  interface {
+    name = "eno1"
+    dhcp = "both"
+  }
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [data.ignition_network.fcos.rendered]
+}
+`
+
+const networkInvalidResource = `
+data "ignition_network" "fcos" {
+  variant = "fcos"
+  vlan {
+    name   = "eno1.10"
+    id     = 10
+    parent = "eno1"
+  }
+}
+`
+
+func TestNetwork(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: networkResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_network.fcos", "rendered", regexp.MustCompile(`path: /etc/NetworkManager/system-connections/eno1.nmconnection`)),
+					r.TestMatchResourceAttr("data.ignition_network.flatcar", "rendered", regexp.MustCompile(`DHCP=yes`)),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`"path":"/etc/hostname"`)),
+				),
+			},
+			{
+				Config:      networkInvalidResource,
+				ExpectError: regexp.MustCompile(`eno1.10: parent eno1 is not an interface, bond or bridge`),
+			},
+		},
+	})
+}
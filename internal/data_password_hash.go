@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/crypt"
+)
+
+func datasourcePasswordHash() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourcePasswordHashRead,
+
+		Schema: map[string]*schema.Schema{
+			"password": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Sensitive:   true,
+				Description: "plaintext password to hash",
+			},
+			"algorithm": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      crypt.Yescrypt,
+				ValidateFunc: validation.StringInSlice([]string{crypt.Yescrypt, crypt.SHA512, crypt.Bcrypt}, false),
+				Description:  "crypt algorithm, one of yescrypt, sha512crypt and bcrypt",
+			},
+			"salt": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"salt", "salt_seed"},
+				Description:  "salt in the encoding of the algorithm",
+			},
+			"salt_seed": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				RequiredWith: []string{"user"},
+				Description:  "secret from which the salt is derived together with user, e.g. a random value of the deployment",
+			},
+			"user": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "name of the user the hash is for, mixed into the salt derived from salt_seed",
+			},
+			"cost": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "rounds of sha512crypt or cost of bcrypt, defaults to the algorithm's default",
+			},
+			"hash": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "crypt hash for passwd.users.password_hash",
+			},
+		},
+	}
+}
+
+func datasourcePasswordHashRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	password := d.Get("password").(string)
+	algorithm := d.Get("algorithm").(string)
+	salt := d.Get("salt").(string)
+	if salt == "" {
+		salt = passwordSalt(algorithm, d.Get("salt_seed").(string), d.Get("user").(string))
+	}
+
+	hash, err := crypt.Password(algorithm, password, salt, d.Get("cost").(int))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("hash", hash); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(hash))
+	return nil
+}
+
+// Derive a salt from a secret seed and the name of a user, so that the hash is stable
+// across plans without storing a random salt. Users with equal passwords get different
+// hashes, and deployments with different seeds get different hashes for the same user.
+func passwordSalt(algorithm, seed, user string) string {
+	mac := hmac.New(sha256.New, []byte(seed))
+	mac.Write([]byte("ignition_password_hash " + algorithm + "\x00" + user))
+	return crypt.EncodeSalt(algorithm, mac.Sum(nil))
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const passwordHashResource = `
+data "ignition_password_hash" "core" {
+  password  = "password"
+  algorithm = "sha512crypt"
+  salt      = "saltsalt"
+}
+`
+
+const passwordHashDerivedSaltResource = `
+data "ignition_password_hash" "core" {
+  password  = "password"
+  salt_seed = "seed"
+  user      = "core"
+}
+`
+
+const passwordHashNoSaltResource = `
+data "ignition_password_hash" "core" {
+  password = "password"
+}
+`
+
+const passwordHashInvalidResource = `
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      password_hash: hunter2
+EOT
+  check_severity = "error"
+}
+`
+
+func TestPasswordHash(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: passwordHashResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_password_hash.core", "hash", "$6$saltsalt$qFmFH.bQmmtXzyBY0s9v7Oicd2z4XSIecDzlB5KiA2/jctKu9YterLp8wwnSq.qc.eoxqOmSuNp2xS0ktL3nh/"),
+				),
+			},
+			{
+				Config: passwordHashDerivedSaltResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_password_hash.core", "hash", regexp.MustCompile(`^\$y\$j9T\$[./0-9A-Za-z]{22}\$[./0-9A-Za-z]{43}$`)),
+				),
+			},
+			{
+				Config:      passwordHashNoSaltResource,
+				ExpectError: regexp.MustCompile(`one of .salt,salt_seed. must be specified`),
+			},
+			{
+				Config:      passwordHashInvalidResource,
+				ExpectError: regexp.MustCompile(`user core \(content\): password_hash is not a yescrypt, sha512crypt, sha256crypt or bcrypt hash`),
+			},
+		},
+	})
+}
+
+func TestPasswordSalt(t *testing.T) {
+	salt := passwordSalt("yescrypt", "seed", "core")
+	if salt != passwordSalt("yescrypt", "seed", "core") {
+		t.Errorf("expected a stable salt")
+	}
+	for _, other := range []string{
+		passwordSalt("yescrypt", "seed", "alice"),
+		passwordSalt("yescrypt", "other", "core"),
+	} {
+		if other == salt {
+			t.Errorf("expected different salts for different users and seeds, got %s twice", salt)
+		}
+	}
+}
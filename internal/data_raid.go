@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// Minimum number of devices of RAID levels supported by mdadm.
+var raidMinDevices = map[string]int{
+	"linear": 1,
+	"raid0":  2,
+	"raid1":  2,
+	"raid4":  3,
+	"raid5":  3,
+	"raid6":  4,
+	"raid10": 2,
+}
+
+func datasourceRaid() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceRaidRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "name of the array, available as /dev/md/<name>",
+			},
+			"level": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(configutil.SortedKeys(raidMinDevices), false),
+				Description:  "RAID level",
+			},
+			"devices": {
+				Type:        schema.TypeList,
+				Required:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateAbsolutePath},
+				Description: "devices of the array",
+			},
+			"spares": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "number of spare devices",
+			},
+			"options": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "additional options for mdadm",
+			},
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "butane snippet for the snippets of ignition_config",
+			},
+		},
+	}
+}
+
+func datasourceRaidRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	level := d.Get("level").(string)
+	raid := types_v3_4.Raid{
+		Name:  d.Get("name").(string),
+		Level: &level,
+	}
+	for _, device := range d.Get("devices").([]interface{}) {
+		name, _ := device.(string)
+		raid.Devices = append(raid.Devices, types_v3_4.Device(name))
+	}
+	if spares, ok := d.GetOkExists("spares"); ok {
+		value := spares.(int)
+		raid.Spares = &value
+	}
+	for _, option := range d.Get("options").([]interface{}) {
+		value, _ := option.(string)
+		raid.Options = append(raid.Options, types_v3_4.RaidOption(value))
+	}
+	spares := 0
+	if raid.Spares != nil {
+		spares = *raid.Spares
+	}
+	if active := len(raid.Devices) - spares; active < raidMinDevices[level] {
+		return diag.Errorf("%s requires at least %d active devices, got %d devices with %d spares", level, raidMinDevices[level], len(raid.Devices), spares)
+	}
+
+	config := types_v3_4.Config{}
+	config.Storage.Raid = []types_v3_4.Raid{raid}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const raidResource = `
+data "ignition_raid" "data" {
+  name    = "data"
+  level   = "raid1"
+  devices = ["/dev/disk/by-partlabel/data-1", "/dev/disk/by-partlabel/data-2"]
+}
+`
+
+const raidSparesResource = `
+data "ignition_raid" "data" {
+  name    = "data"
+  level   = "raid5"
+  devices = ["/dev/vdb", "/dev/vdc", "/dev/vdd"]
+  spares  = 1
+}
+`
+
+func TestRaid(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: raidResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_raid.data", "rendered", regexp.MustCompile(`level: raid1`)),
+				),
+			},
+			{
+				Config:      raidSparesResource,
+				ExpectError: regexp.MustCompile(`raid5 requires at least 3 active devices, got 3 devices with 1 spares`),
+			},
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func datasourceSystemdDropin() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceSystemdDropinRead,
+
+		Schema: unitContentsArguments(map[string]*schema.Schema{
+			"unit": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateUnitName,
+				Description:  "name of the unit of the dropin, e.g. foo.service",
+			},
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "name of the dropin, e.g. 10-override.conf",
+			},
+		}),
+	}
+}
+
+func datasourceSystemdDropinRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	name := d.Get("name").(string)
+	if !strings.HasSuffix(name, ".conf") || strings.Contains(name, "/") {
+		return diag.Errorf("name must be a file name ending with .conf, got %q", name)
+	}
+	contents, err := expandUnitContents(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if contents == nil {
+		return diag.Errorf("dropin %s requires contents or a section", name)
+	}
+	config := types_v3_4.Config{}
+	config.Systemd.Units = []types_v3_4.Unit{{
+		Name:    d.Get("unit").(string),
+		Dropins: []types_v3_4.Dropin{{Name: name, Contents: contents}},
+	}}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
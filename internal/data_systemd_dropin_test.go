@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const systemdDropinResource = `
+data "ignition_systemd_dropin" "docker" {
+  unit     = "docker.service"
+  name     = "10-proxy.conf"
+  contents = "[Service]\nEnvironment=HTTP_PROXY=http://proxy:3128\n"
+}
+`
+
+func TestSystemdDropin(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: systemdDropinResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_systemd_dropin.docker", "rendered", regexp.MustCompile(`- name: 10-proxy.conf\n`)),
+				),
+			},
+			{
+				Config:      `data "ignition_systemd_dropin" "docker" { unit = "docker.service", name = "proxy" }`,
+				ExpectError: regexp.MustCompile(`name must be a file name ending with .conf`),
+			},
+		},
+	})
+}
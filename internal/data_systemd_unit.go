@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+// Unit names as accepted by systemd, including templates (foo@.service) and instances (foo@bar.service).
+var unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\-]+(@[A-Za-z0-9:_.\\-]*)?\.(service|socket|device|mount|automount|swap|target|path|timer|slice|scope)$`)
+
+var validateUnitName = validation.StringMatch(unitNamePattern, "must be a unit name such as foo.service or foo@.service")
+
+// Arguments for the contents of a unit or dropin, either verbatim or as sections.
+func unitContentsArguments(elem map[string]*schema.Schema) map[string]*schema.Schema {
+	elem["contents"] = &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		ConflictsWith: []string{"section"},
+		Description:   "contents of the unit file",
+	}
+	elem["section"] = &schema.Schema{
+		Type:          schema.TypeList,
+		Optional:      true,
+		ConflictsWith: []string{"contents"},
+		Description:   "sections of the unit file, rendered in order",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "name of the section, e.g. Service",
+				},
+				"entry": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Description: "directives of the section, keys may repeat",
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"key": {
+								Type:        schema.TypeString,
+								Required:    true,
+								Description: "name of the directive, e.g. ExecStart",
+							},
+							"value": {
+								Type:        schema.TypeString,
+								Required:    true,
+								Description: "value of the directive",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	elem["rendered"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "butane snippet for the snippets of ignition_config",
+	}
+	return elem
+}
+
+// Contents of a unit or dropin from the contents or section arguments, nil if neither is set.
+func expandUnitContents(d *schema.ResourceData) (*string, error) {
+	if contents := d.Get("contents").(string); contents != "" {
+		return &contents, nil
+	}
+	sections := d.Get("section").([]interface{})
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	var b strings.Builder
+	for i, v := range sections {
+		section, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := section["name"].(string)
+		if name == "" || strings.ContainsAny(name, "[]\n") {
+			return nil, fmt.Errorf("section %d: invalid name %q", i, name)
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", name)
+		for _, e := range section["entry"].([]interface{}) {
+			entry, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, value := entry["key"].(string), entry["value"].(string)
+			if key == "" || strings.ContainsAny(key, "=\n") || strings.TrimSpace(key) != key {
+				return nil, fmt.Errorf("section %s: invalid key %q", name, key)
+			}
+			if strings.Contains(value, "\n") {
+				return nil, fmt.Errorf("section %s: value of %s contains a newline", name, key)
+			}
+			fmt.Fprintf(&b, "%s=%s\n", key, value)
+		}
+	}
+	contents := b.String()
+	return &contents, nil
+}
+
+func datasourceSystemdUnit() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceSystemdUnitRead,
+
+		Schema: unitContentsArguments(map[string]*schema.Schema{
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateUnitName,
+				Description:  "name of the unit, e.g. foo.service",
+			},
+			"enabled": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to enable the unit, unset leaves the preset of the OS",
+			},
+			"mask": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "whether to mask the unit",
+			},
+			"instances": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "instances of a template unit to enable",
+			},
+		}),
+	}
+}
+
+func datasourceSystemdUnitRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	name := d.Get("name").(string)
+	contents, err := expandUnitContents(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	unit := types_v3_4.Unit{Name: name, Contents: contents, Enabled: optionalBool(d, "enabled")}
+	if d.Get("mask").(bool) {
+		mask := true
+		unit.Mask = &mask
+	}
+	config := types_v3_4.Config{}
+	config.Systemd.Units = []types_v3_4.Unit{unit}
+
+	instances := d.Get("instances").([]interface{})
+	if len(instances) > 0 {
+		prefix, suffix, template := strings.Cut(name, "@.")
+		if !template {
+			return diag.Errorf("instances require a template unit such as foo@.service, got %s", name)
+		}
+		if unit.Mask != nil {
+			return diag.Errorf("instances of the masked unit %s cannot be enabled", name)
+		}
+		for _, v := range instances {
+			instance, _ := v.(string)
+			instanceName := prefix + "@" + instance + "." + suffix
+			if instance == "" || !unitNamePattern.MatchString(instanceName) {
+				return diag.Errorf("invalid instance %q of %s", instance, name)
+			}
+			enabled := true
+			config.Systemd.Units = append(config.Systemd.Units, types_v3_4.Unit{Name: instanceName, Enabled: &enabled})
+		}
+	}
+
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(rendered))
+	return nil
+}
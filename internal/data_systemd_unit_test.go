@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const systemdUnitResource = `
+data "ignition_systemd_unit" "hello" {
+  name    = "hello.service"
+  enabled = true
+  section {
+    name = "Service"
+    entry {
+      key   = "Type"
+      value = "oneshot"
+    }
+    entry {
+      key   = "ExecStart"
+      value = "/usr/bin/echo hello"
+    }
+  }
+  section {
+    name = "Install"
+    entry {
+      key   = "WantedBy"
+      value = "multi-user.target"
+    }
+  }
+}
+
+data "ignition_systemd_unit" "getty" {
+  name      = "serial-getty@.service"
+  instances = ["ttyS0", "ttyS1"]
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [
+    data.ignition_systemd_unit.hello.rendered,
+    data.ignition_systemd_unit.getty.rendered,
+  ]
+}
+`
+
+const systemdUnitInstancesResource = `
+data "ignition_systemd_unit" "hello" {
+  name      = "hello.service"
+  instances = ["a"]
+}
+`
+
+func TestSystemdUnit(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: systemdUnitResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestMatchResourceAttr("data.ignition_systemd_unit.hello", "rendered", regexp.MustCompile(`\[Service\]\n +Type=oneshot\n +ExecStart=/usr/bin/echo hello\n\n +\[Install\]`)),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`"name":"serial-getty@ttyS1.service"`)),
+				),
+			},
+			{
+				Config:      systemdUnitInstancesResource,
+				ExpectError: regexp.MustCompile(`instances require a template unit`),
+			},
+		},
+	})
+}
@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	butane "github.com/coreos/butane/config"
+	"github.com/coreos/butane/config/common"
+	fcos_v1_0 "github.com/coreos/butane/config/fcos/v1_0"
+	fcos_v1_1 "github.com/coreos/butane/config/fcos/v1_1"
+	fcos_v1_2 "github.com/coreos/butane/config/fcos/v1_2"
+	fcos_v1_3 "github.com/coreos/butane/config/fcos/v1_3"
+	fcos_v1_4 "github.com/coreos/butane/config/fcos/v1_4"
+	fcos_v1_5 "github.com/coreos/butane/config/fcos/v1_5"
+	flatcar_v1_0 "github.com/coreos/butane/config/flatcar/v1_0"
+	flatcar_v1_1 "github.com/coreos/butane/config/flatcar/v1_1"
+	"github.com/coreos/go-semver/semver"
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+type butaneVersion struct {
+	version         string
+	ignitionVersion string
+	config          func() interface{}
+}
+
+// Stable Butane versions per variant in ascending order, with the Ignition version they produce.
+var butaneVersions = map[string][]butaneVersion{
+	"fcos": {
+		{"1.0.0", "3.0.0", func() interface{} { return &fcos_v1_0.Config{} }},
+		{"1.1.0", "3.1.0", func() interface{} { return &fcos_v1_1.Config{} }},
+		{"1.2.0", "3.2.0", func() interface{} { return &fcos_v1_2.Config{} }},
+		{"1.3.0", "3.2.0", func() interface{} { return &fcos_v1_3.Config{} }},
+		{"1.4.0", "3.3.0", func() interface{} { return &fcos_v1_4.Config{} }},
+		{"1.5.0", "3.4.0", func() interface{} { return &fcos_v1_5.Config{} }},
+	},
+	"flatcar": {
+		{"1.0.0", "3.3.0", func() interface{} { return &flatcar_v1_0.Config{} }},
+		{"1.1.0", "3.4.0", func() interface{} { return &flatcar_v1_1.Config{} }},
+	},
+}
+
+// Pick the requested Butane version, or the newest one producing the Ignition
+// version of the config. Falls back to the oldest Butane version producing a newer Ignition version.
+func getButaneVersion(variant, version string, ignitionVersion semver.Version) (butaneVersion, error) {
+	versions, ok := butaneVersions[variant]
+	if !ok {
+		return butaneVersion{}, fmt.Errorf("unsupported variant %s", variant)
+	}
+	if version != "" {
+		for _, v := range versions {
+			if v.version == version {
+				return v, nil
+			}
+		}
+		return butaneVersion{}, fmt.Errorf("unsupported version %s for variant %s", version, variant)
+	}
+	var match *butaneVersion
+	for i, v := range versions {
+		if v.ignitionVersion == ignitionVersion.String() {
+			match = &versions[i]
+		}
+	}
+	if match != nil {
+		return *match, nil
+	}
+	for _, v := range versions {
+		if ignitionVersion.LessThan(*semver.New(v.ignitionVersion)) {
+			return v, nil
+		}
+	}
+	return butaneVersion{}, fmt.Errorf("variant %s does not support ignition version %s", variant, ignitionVersion.String())
+}
+
+func datasourceToButane() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceToButaneRead,
+
+		Schema: map[string]*schema.Schema{
+			"content": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "rendered ignition configuration to convert",
+			},
+			"variant": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"fcos", "flatcar"}, false),
+			},
+			"version": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "butane version, defaults to the newest version producing the ignition version of the content",
+			},
+			"butane": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "butane configuration",
+			},
+			"unsupported": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "ignition fields without a butane equivalent, which were dropped",
+			},
+		},
+	}
+}
+
+func datasourceToButaneRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	content := d.Get("content").(string)
+	config, err := configutil.ParseRendered([]byte(content))
+	if err != nil {
+		return diag.FromErr(fmt.Errorf("content parse error: %v", err))
+	}
+	ignitionVersion, _, err := ignition_util.GetConfigVersion([]byte(content))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	target, err := getButaneVersion(d.Get("variant").(string), d.Get("version").(string), ignitionVersion)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	butaneBytes, unsupported, err := ignitionToButane(config, d.Get("variant").(string), target)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(unsupported) > 0 {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("fields without an equivalent in %s %s were dropped", d.Get("variant").(string), target.version),
+			Detail:   strings.Join(unsupported, "\n"),
+		})
+	} else if differences, err := butaneRoundTrip(butaneBytes, config); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("butane configuration is not valid for %s %s", d.Get("variant").(string), target.version),
+			Detail:   err.Error(),
+		})
+	} else if differences != "" {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  "butane configuration does not transpile to a semantically identical ignition configuration",
+			Detail:   differences,
+		})
+	}
+
+	if err := d.Set("butane", string(butaneBytes)); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := d.Set("unsupported", unsupported); err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(hashcode(string(butaneBytes)))
+	return diags
+}
+
+// Convert an Ignition config to the Butane config of the given variant and version.
+// Returns the paths of Ignition fields which could not be converted.
+func ignitionToButane(config types_v3_4.Config, variant string, target butaneVersion) ([]byte, []string, error) {
+	butaneConfig := target.config()
+	value := reflect.ValueOf(butaneConfig).Elem()
+	value.FieldByName("Variant").SetString(variant)
+	value.FieldByName("Version").SetString(target.version)
+
+	var unsupported []string
+	copyToButane(reflect.ValueOf(config), value, "", &unsupported)
+
+	var document yaml.Node
+	document.Kind = yaml.DocumentNode
+	document.Content = []*yaml.Node{butaneNode(value, "")}
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, err
+	}
+	return []byte(b.String()), unsupported, nil
+}
+
+// Transpile the Butane config back to Ignition and describe how it differs from the original config.
+func butaneRoundTrip(butaneBytes []byte, original types_v3_4.Config) (string, error) {
+	ignitionBytes, report, err := butane.TranslateBytes(butaneBytes, common.TranslateBytesOptions{})
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", err, report.String())
+	}
+	roundTrip, err := configutil.ParseRendered(ignitionBytes)
+	if err != nil {
+		return "", err
+	}
+	diff := diffConfigs(original, roundTrip)
+	if !diff.changed {
+		return "", nil
+	}
+	return diff.summary(), nil
+}
+
+var resourceType = reflect.TypeOf(types_v3_4.Resource{})
+
+// Recursively copy an Ignition config value to the Butane field of the same name.
+// Inline resources are decoded into Butane inline text.
+func copyToButane(from, to reflect.Value, path string, unsupported *[]string) {
+	switch from.Kind() {
+	case reflect.Struct:
+		if to.Kind() != reflect.Struct {
+			*unsupported = append(*unsupported, path)
+			return
+		}
+		for i := 0; i < from.NumField(); i++ {
+			field := from.Type().Field(i)
+			if field.Anonymous {
+				copyToButane(from.Field(i), to, path, unsupported)
+				continue
+			}
+			if from.Field(i).IsZero() || (path == "ignition" && field.Name == "Version") {
+				continue
+			}
+			fieldPath := joinPath(path, strings.Split(field.Tag.Get("json"), ",")[0])
+			target := to.FieldByName(field.Name)
+			if !target.IsValid() {
+				*unsupported = append(*unsupported, fieldPath)
+				continue
+			}
+			copyToButane(from.Field(i), target, fieldPath, unsupported)
+		}
+		if from.Type() == resourceType {
+			inlineResource(from.Interface().(types_v3_4.Resource), to)
+		}
+	case reflect.Ptr:
+		if from.IsNil() {
+			return
+		}
+		if to.Kind() != reflect.Ptr {
+			copyToButane(from.Elem(), to, path, unsupported)
+			return
+		}
+		to.Set(reflect.New(to.Type().Elem()))
+		copyToButane(from.Elem(), to.Elem(), path, unsupported)
+	case reflect.Slice:
+		if to.Kind() != reflect.Slice {
+			*unsupported = append(*unsupported, path)
+			return
+		}
+		to.Set(reflect.MakeSlice(to.Type(), from.Len(), from.Len()))
+		for i := 0; i < from.Len(); i++ {
+			copyToButane(from.Index(i), to.Index(i), fmt.Sprintf("%s[%d]", path, i), unsupported)
+		}
+	default:
+		if to.Kind() == reflect.Ptr {
+			to.Set(reflect.New(to.Type().Elem()))
+			to = to.Elem()
+		}
+		if !from.Type().ConvertibleTo(to.Type()) {
+			*unsupported = append(*unsupported, path)
+			return
+		}
+		to.Set(from.Convert(to.Type()))
+	}
+}
+
+// Replace a data URL source with Butane inline text. Verified and binary
+// resources are kept as they are, re-encoding them would change their hash or contents.
+func inlineResource(resource types_v3_4.Resource, to reflect.Value) {
+	if resource.Verification.Hash != nil {
+		return
+	}
+	contents, ok, err := configutil.DecodeResource(resource)
+	if err != nil || !ok || !utf8.Valid(contents) || resource.Source == nil {
+		return
+	}
+	inline := string(contents)
+	to.FieldByName("Inline").Set(reflect.ValueOf(&inline))
+	to.FieldByName("Source").Set(reflect.Zero(to.FieldByName("Source").Type()))
+	to.FieldByName("Compression").Set(reflect.Zero(to.FieldByName("Compression").Type()))
+}
+
+// Build a YAML node of a Butane config value, leaving out empty fields.
+// Returns nil for empty values.
+func butaneNode(value reflect.Value, name string) *yaml.Node {
+	switch value.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			tag := strings.Split(field.Tag.Get("yaml"), ",")
+			if tag[0] == "-" {
+				continue
+			}
+			child := butaneNode(value.Field(i), field.Name)
+			if child == nil {
+				continue
+			}
+			if len(tag) > 1 && tag[1] == "inline" {
+				node.Content = append(node.Content, child.Content...)
+				continue
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag[0]}, child)
+		}
+		if len(node.Content) == 0 {
+			return nil
+		}
+		sortButaneKeys(node)
+		return node
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		if name == "Mode" {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprintf("%04o", value.Elem().Int())}
+		}
+		node := &yaml.Node{}
+		if err := node.Encode(value.Elem().Interface()); err != nil {
+			return nil
+		}
+		if value.Elem().Kind() == reflect.String && strings.Contains(value.Elem().String(), "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		return node
+	case reflect.Slice:
+		if value.Len() == 0 {
+			return nil
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for i := 0; i < value.Len(); i++ {
+			child := butaneNode(value.Index(i), "")
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node
+	default:
+		if value.IsZero() {
+			return nil
+		}
+		node := &yaml.Node{}
+		if err := node.Encode(value.Interface()); err != nil {
+			return nil
+		}
+		return node
+	}
+}
+
+// Keys identifying an entry come first, like in the Butane documentation.
+var butaneLeadingKeys = []string{"version", "variant", "name", "path", "device", "number", "label"}
+
+func sortButaneKeys(node *yaml.Node) {
+	rank := func(i int) int {
+		for r, key := range butaneLeadingKeys {
+			if node.Content[i*2].Value == key {
+				return r
+			}
+		}
+		return len(butaneLeadingKeys)
+	}
+	pairs := len(node.Content) / 2
+	indices := make([]int, pairs)
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool { return rank(indices[a]) < rank(indices[b]) })
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for _, i := range indices {
+		content = append(content, node.Content[i*2], node.Content[i*2+1])
+	}
+	node.Content = content
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
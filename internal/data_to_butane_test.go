@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const toButaneResource = `
+data "ignition_config" "fedora-coreos" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key
+storage:
+  files:
+    - path: /etc/motd
+      mode: 0644
+      contents:
+        inline: |
+          hello
+          world
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+EOT
+}
+
+data "ignition_to_butane" "flatcar" {
+  content = data.ignition_config.fedora-coreos.rendered
+  variant = "flatcar"
+}
+
+data "ignition_config" "round-trip" {
+  content = data.ignition_to_butane.flatcar.butane
+}
+`
+
+const toButaneExpected = `version: 1.1.0
+variant: flatcar
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - key
+storage:
+  files:
+    - path: /etc/motd
+      contents:
+        inline: |
+          hello
+          world
+      mode: 0644
+systemd:
+  units:
+    - name: docker.service
+      enabled: true
+`
+
+func TestToButane(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: toButaneResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_to_butane.flatcar", "butane", toButaneExpected),
+					r.TestCheckResourceAttr("data.ignition_to_butane.flatcar", "unsupported.#", "0"),
+					r.TestCheckResourceAttrPair("data.ignition_config.round-trip", "rendered", "data.ignition_config.fedora-coreos", "rendered"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/crypt"
+)
+
+// Account names accepted by useradd and groupadd.
+var validateAccountName = validation.StringMatch(regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,30}\$?$`), "must be a lower case account name of at most 32 characters")
+
+func datasourceUser() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: datasourceUserRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAccountName,
+				Description:  "user name",
+			},
+			"uid": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Description: "user ID, allocated by useradd if not set",
+			},
+			"password_hash": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "crypt hash of the password, e.g. of the ignition_password_hash data source",
+			},
+			"ssh_authorized_keys": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "SSH public keys in authorized_keys format",
+			},
+			"gecos": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "comment of the user, usually the full name",
+			},
+			"home_dir": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "home directory of the user",
+			},
+			"primary_group": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "name of the primary group of the user",
+			},
+			"groups": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "supplementary groups of the user",
+			},
+			"shell": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateAbsolutePath,
+				Description:  "login shell of the user",
+			},
+			"system": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to create a system account",
+			},
+			"no_create_home": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to skip creating the home directory",
+			},
+			"no_user_group": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to skip creating a group of the same name",
+			},
+			"no_log_init": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "whether to skip adding the user to the lastlog and faillog databases",
+			},
+			"ssh_keys": computedList(map[string]*schema.Schema{
+				"algorithm":   computedString(),
+				"bits":        computedInt(),
+				"fingerprint": computedString(),
+				"comment":     computedString(),
+			}),
+			"rendered": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "butane snippet for the snippets of ignition_config, sensitive as it may contain the password hash",
+			},
+		},
+	}
+}
+
+func datasourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	user := types_v3_4.PasswdUser{Name: d.Get("name").(string)}
+	if uid, ok := d.GetOkExists("uid"); ok {
+		value := uid.(int)
+		user.UID = &value
+	}
+	user.Gecos = optionalString(d, "gecos")
+	user.HomeDir = optionalString(d, "home_dir")
+	user.PrimaryGroup = optionalString(d, "primary_group")
+	user.Shell = optionalString(d, "shell")
+	user.System = optionalBool(d, "system")
+	user.NoCreateHome = optionalBool(d, "no_create_home")
+	user.NoUserGroup = optionalBool(d, "no_user_group")
+	user.NoLogInit = optionalBool(d, "no_log_init")
+	for _, group := range d.Get("groups").([]interface{}) {
+		name, _ := group.(string)
+		user.Groups = append(user.Groups, types_v3_4.Group(name))
+	}
+
+	diags := passwordHashDiagnostics(d.Get("password_hash").(string))
+	if diags.HasError() {
+		return diags
+	}
+	user.PasswordHash = optionalString(d, "password_hash")
+
+	var keys []interface{}
+	fingerprints := map[string]bool{}
+	for i, v := range d.Get("ssh_authorized_keys").([]interface{}) {
+		line, _ := v.(string)
+		key, err := parseAuthorizedKey(line)
+		if err != nil {
+			return append(diags, diag.FromErr(fmt.Errorf("ssh_authorized_keys %d: %v", i, err))...)
+		}
+		if fingerprints[key.fingerprint] {
+			return append(diags, diag.Errorf("ssh_authorized_keys %d: duplicate key %s", i, key.fingerprint)...)
+		}
+		fingerprints[key.fingerprint] = true
+		user.SSHAuthorizedKeys = append(user.SSHAuthorizedKeys, types_v3_4.SSHAuthorizedKey(line))
+		keys = append(keys, map[string]interface{}{
+			"algorithm":   key.algorithm,
+			"bits":        key.bits,
+			"fingerprint": key.fingerprint,
+			"comment":     key.comment,
+		})
+	}
+	if err := d.Set("ssh_keys", keys); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+
+	config := types_v3_4.Config{}
+	config.Passwd.Users = []types_v3_4.PasswdUser{user}
+	rendered, err := renderFragment(config)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	if err := d.Set("rendered", rendered); err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	d.SetId(hashcode(rendered))
+	return diags
+}
+
+// Check the password hash of a user or group, weak algorithms are reported as warnings.
+func passwordHashDiagnostics(hash string) diag.Diagnostics {
+	if hash == "" {
+		return nil
+	}
+	problem, weak := crypt.HashProblem(hash)
+	if problem == "" {
+		return nil
+	}
+	if weak {
+		return diag.Diagnostics{{Severity: diag.Warning, Summary: "weak password hash", Detail: problem}}
+	}
+	return diag.Diagnostics{{Severity: diag.Error, Summary: "invalid password hash", Detail: problem}}
+}
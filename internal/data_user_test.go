@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const userResource = `
+data "ignition_user" "alice" {
+  name                = "alice"
+  uid                 = 1001
+  groups              = ["wheel"]
+  ssh_authorized_keys = ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGR+R78UWbJfP9oemfHApBkAdUFxrYh4Aryi3v5R0ZnJ alice@example.com"]
+}
+
+data "ignition_group" "admins" {
+  name   = "admins"
+  system = true
+}
+
+data "ignition_config" "example" {
+  content = <<EOT
+---
+variant: fcos
+version: 1.5.0
+EOT
+  snippets = [
+    data.ignition_user.alice.rendered,
+    data.ignition_group.admins.rendered,
+  ]
+}
+`
+
+const userInvalidKeyResource = `
+data "ignition_user" "alice" {
+  name                = "alice"
+  ssh_authorized_keys = ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGR+R78UWbJfP9oemfHApBkAdUFxrYh4Aryi3v5R0Zn alice@example.com"]
+}
+`
+
+func TestUser(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config: userResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttr("data.ignition_user.alice", "ssh_keys.0.algorithm", "ssh-ed25519"),
+					r.TestCheckResourceAttr("data.ignition_user.alice", "ssh_keys.0.bits", "256"),
+					r.TestCheckResourceAttr("data.ignition_user.alice", "ssh_keys.0.fingerprint", "SHA256:L08QmLa/+uJhXStDo1MyFVVeBxUDMxbs0OcaX5yRBto"),
+					r.TestMatchResourceAttr("data.ignition_config.example", "rendered", regexp.MustCompile(`"groups":\[\{"name":"admins","system":true\}\]`)),
+				),
+			},
+			{
+				Config:      userInvalidKeyResource,
+				ExpectError: regexp.MustCompile(`ssh_authorized_keys 0: invalid SSH public key`),
+			},
+		},
+	})
+}
@@ -0,0 +1,276 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	butane "github.com/coreos/butane/config"
+	"github.com/coreos/butane/config/common"
+	"github.com/coreos/go-semver/semver"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/vincent-petithory/dataurl"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// Butane variant of fragments. Fragments only use fields which are the same in all
+// variants, so they can be merged into configs of any variant.
+const fragmentVariant = "fcos"
+
+var verificationPattern = regexp.MustCompile(`^(sha512-[0-9a-f]{128}|sha256-[0-9a-f]{64})$`)
+
+// Arguments shared by the file, directory and link data sources.
+func nodeArguments(elem map[string]*schema.Schema) map[string]*schema.Schema {
+	elem["path"] = &schema.Schema{
+		Type:         schema.TypeString,
+		Required:     true,
+		ValidateFunc: validateAbsolutePath,
+		Description:  "absolute path of the node",
+	}
+	elem["overwrite"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Description: "whether to replace a preexisting node at the path",
+	}
+	elem["user_id"] = &schema.Schema{
+		Type:          schema.TypeInt,
+		Optional:      true,
+		ConflictsWith: []string{"user_name"},
+		Description:   "owner user ID",
+	}
+	elem["user_name"] = &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		ConflictsWith: []string{"user_id"},
+		Description:   "owner user name",
+	}
+	elem["group_id"] = &schema.Schema{
+		Type:          schema.TypeInt,
+		Optional:      true,
+		ConflictsWith: []string{"group_name"},
+		Description:   "owner group ID",
+	}
+	elem["group_name"] = &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		ConflictsWith: []string{"group_id"},
+		Description:   "owner group name",
+	}
+	elem["rendered"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Description: "butane snippet for the snippets of ignition_config",
+	}
+	return elem
+}
+
+func modeArgument(description string) *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeInt,
+		Optional:     true,
+		ValidateFunc: validation.IntBetween(0, 07777),
+		Description:  description,
+	}
+}
+
+func validateAbsolutePath(value interface{}, key string) ([]string, []error) {
+	p := value.(string)
+	if !path.IsAbs(p) {
+		return nil, []error{fmt.Errorf("%s must be an absolute path, got %q", key, p)}
+	}
+	if path.Clean(p) != p {
+		return nil, []error{fmt.Errorf("%s must be a clean path, got %q instead of %q", key, p, path.Clean(p))}
+	}
+	return nil, nil
+}
+
+func expandNode(d *schema.ResourceData) types_v3_4.Node {
+	node := types_v3_4.Node{
+		Path:      d.Get("path").(string),
+		Overwrite: optionalBool(d, "overwrite"),
+	}
+	if id, ok := d.GetOkExists("user_id"); ok {
+		value := id.(int)
+		node.User.ID = &value
+	}
+	if name := d.Get("user_name").(string); name != "" {
+		node.User.Name = &name
+	}
+	if id, ok := d.GetOkExists("group_id"); ok {
+		value := id.(int)
+		node.Group.ID = &value
+	}
+	if name := d.Get("group_name").(string); name != "" {
+		node.Group.Name = &name
+	}
+	return node
+}
+
+func optionalString(d *schema.ResourceData, key string) *string {
+	if value := d.Get(key).(string); value != "" {
+		return &value
+	}
+	return nil
+}
+
+// Booleans which are unset stay nil, as unset and false differ when merging snippets.
+func optionalBool(d *schema.ResourceData, key string) *bool {
+	if value, ok := d.GetOkExists(key); ok {
+		b := value.(bool)
+		return &b
+	}
+	return nil
+}
+
+func expandMode(d *schema.ResourceData) *int {
+	if mode, ok := d.GetOkExists("mode"); ok {
+		value := mode.(int)
+		return &value
+	}
+	return nil
+}
+
+// Build the resource of inline content or a remote source. Compression describes
+// the remote source, inline content is compressed by Butane if that saves space.
+// The verification hash describes the uncompressed contents and is checked for inline content.
+func expandResource(content, source, compression, verification string) (types_v3_4.Resource, error) {
+	var resource types_v3_4.Resource
+	if content != "" && source != "" {
+		return resource, fmt.Errorf("content and source are mutually exclusive")
+	}
+	if compression != "" {
+		if source == "" {
+			return resource, fmt.Errorf("compression requires a source, inline content is compressed automatically")
+		}
+		resource.Compression = &compression
+	}
+	if source != "" {
+		if err := validateSource(source); err != nil {
+			return resource, err
+		}
+		resource.Source = &source
+	}
+	if content != "" {
+		encoded := dataurl.EncodeBytes([]byte(content))
+		resource.Source = &encoded
+	}
+	if verification != "" {
+		if !verificationPattern.MatchString(verification) {
+			return resource, fmt.Errorf("verification must be sha512-<hex> or sha256-<hex>, got %q", verification)
+		}
+		if resource.Source == nil {
+			return resource, fmt.Errorf("verification requires content or a source")
+		}
+		if content != "" {
+			if expected := contentHash(verification, []byte(content)); expected != verification {
+				return resource, fmt.Errorf("verification %s does not match the content, which has %s", verification, expected)
+			}
+		}
+		resource.Verification.Hash = &verification
+	}
+	return resource, nil
+}
+
+func contentHash(verification string, contents []byte) string {
+	if strings.HasPrefix(verification, "sha256-") {
+		sum := sha256.Sum256(contents)
+		return "sha256-" + hex.EncodeToString(sum[:])
+	}
+	sum := sha512.Sum512(contents)
+	return "sha512-" + hex.EncodeToString(sum[:])
+}
+
+func validateSource(source string) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid source %q: %v", source, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "tftp", "s3", "gs", "arn", "data":
+		return nil
+	}
+	return fmt.Errorf("invalid source %q: the scheme must be one of http, https, tftp, s3, gs, arn and data", source)
+}
+
+// The oldest Ignition version supporting the features used by a fragment.
+func fragmentIgnitionVersion(config types_v3_4.Config) semver.Version {
+	version := *semver.New("3.0.0")
+	require := func(minimum string) {
+		if v := *semver.New(minimum); version.LessThan(v) {
+			version = v
+		}
+	}
+	var resources []types_v3_4.Resource
+	for _, file := range config.Storage.Files {
+		resources = append(resources, file.Contents)
+		resources = append(resources, file.Append...)
+	}
+	for _, luks := range config.Storage.Luks {
+		resources = append(resources, luks.KeyFile)
+	}
+	for _, resource := range resources {
+		if strings.HasPrefix(configutil.StringValue(resource.Verification.Hash), "sha256-") {
+			require("3.1.0")
+		}
+		switch strings.SplitN(configutil.StringValue(resource.Source), ":", 2)[0] {
+		case "gs":
+			require("3.2.0")
+		case "arn":
+			require("3.4.0")
+		}
+	}
+	for _, disk := range config.Storage.Disks {
+		for _, partition := range disk.Partitions {
+			if partition.Resize != nil {
+				require("3.2.0")
+			}
+		}
+	}
+	for _, filesystem := range config.Storage.Filesystems {
+		if len(filesystem.MountOptions) > 0 {
+			require("3.1.0")
+		}
+	}
+	for _, luks := range config.Storage.Luks {
+		require("3.2.0")
+		if luks.Discard != nil || len(luks.OpenOptions) > 0 {
+			require("3.4.0")
+		}
+		for _, tang := range luks.Clevis.Tang {
+			if tang.Advertisement != nil {
+				require("3.4.0")
+			}
+		}
+	}
+	return version
+}
+
+// Render a config fragment as a Butane snippet of the oldest version supporting it,
+// so that it can be merged into content of any newer version. The snippet is
+// transpiled to make sure it is valid.
+func renderFragment(config types_v3_4.Config) (string, error) {
+	target, err := getButaneVersion(fragmentVariant, "", fragmentIgnitionVersion(config))
+	if err != nil {
+		return "", err
+	}
+	butaneBytes, unsupported, err := ignitionToButane(config, fragmentVariant, target)
+	if err != nil {
+		return "", err
+	}
+	if len(unsupported) > 0 {
+		return "", fmt.Errorf("fields without an equivalent in %s %s: %s", fragmentVariant, target.version, strings.Join(unsupported, ", "))
+	}
+	if _, report, err := butane.TranslateBytes(butaneBytes, common.TranslateBytesOptions{}); err != nil {
+		return "", fmt.Errorf("%v: %s", err, report.String())
+	}
+	return string(butaneBytes), nil
+}
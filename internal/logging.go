@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// Number of diagnostics by severity.
+func diagnosticFields(diags diag.Diagnostics) map[string]interface{} {
+	var errors, warnings int
+	for _, d := range diags {
+		if d.Severity == diag.Error {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return map[string]interface{}{"errors": errors, "warnings": warnings}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// A network link of the variant-neutral description of ignition_network.
+type networkLink struct {
+	// ethernet, bond, bridge or vlan
+	kind       string
+	name       string
+	macAddress string
+	// bond or bridge the link is a member of
+	master    string
+	dhcp      string
+	addresses []string
+	gateways  []string
+	routes    []networkRoute
+	dns       []string
+	dnsSearch []string
+	mtu       int
+
+	bondMode    string
+	bondOptions map[string]string
+	stp         bool
+	vlanID      int
+	parent      string
+}
+
+type networkRoute struct {
+	destination string
+	gateway     string
+	metric      int
+}
+
+// A file of the rendered network configuration.
+type networkFile struct {
+	path     string
+	mode     int
+	contents string
+}
+
+const (
+	dhcpNone = "none"
+	dhcpIPv4 = "ipv4"
+	dhcpIPv6 = "ipv6"
+	dhcpBoth = "both"
+)
+
+var bondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad", "balance-tlb", "balance-alb"}
+
+// Bond options of the kernel, which NetworkManager uses, and their systemd-networkd names.
+var networkdBondOptions = map[string]string{
+	"miimon":           "MIIMonitorSec",
+	"updelay":          "UpDelaySec",
+	"downdelay":        "DownDelaySec",
+	"lacp_rate":        "LACPTransmitRate",
+	"xmit_hash_policy": "TransmitHashPolicy",
+	"ad_select":        "AdSelect",
+	"arp_interval":     "ARPIntervalSec",
+	"arp_ip_target":    "ARPIPTargets",
+	"min_links":        "MinLinks",
+	"primary_reselect": "PrimaryReselectPolicy",
+}
+
+// Options of systemd-networkd which take a time span, the kernel options are in milliseconds.
+var networkdBondMilliseconds = map[string]bool{"miimon": true, "updelay": true, "downdelay": true, "arp_interval": true}
+
+// Options which are comma-separated lists for the kernel and space-separated ones for systemd-networkd.
+var networkdBondLists = map[string]bool{"arp_ip_target": true}
+
+// Check the references and addresses of links. Names must be unique, members
+// must refer to a bond or bridge and VLANs to another link.
+func validateNetwork(links []networkLink) error {
+	byName := map[string]networkLink{}
+	for _, link := range links {
+		if _, ok := byName[link.name]; ok {
+			return fmt.Errorf("%s is defined more than once", link.name)
+		}
+		byName[link.name] = link
+	}
+	for _, link := range links {
+		if link.master != "" {
+			master, ok := byName[link.master]
+			if !ok || (master.kind != "bond" && master.kind != "bridge") {
+				return fmt.Errorf("%s: master %s is not a bond or bridge", link.name, link.master)
+			}
+			if link.kind == "bond" && master.kind == "bond" {
+				return fmt.Errorf("%s: a bond cannot be a member of bond %s", link.name, link.master)
+			}
+			if link.dhcp != dhcpNone || len(link.addresses) > 0 || len(link.gateways) > 0 || len(link.routes) > 0 || len(link.dns) > 0 {
+				return fmt.Errorf("%s: members of %s cannot have addresses, routes or DNS", link.name, link.master)
+			}
+		}
+		if link.kind == "vlan" {
+			parent, ok := byName[link.parent]
+			if !ok || parent.kind == "vlan" {
+				return fmt.Errorf("%s: parent %s is not an interface, bond or bridge", link.name, link.parent)
+			}
+		}
+		for _, address := range link.addresses {
+			if _, err := netip.ParsePrefix(address); err != nil {
+				return fmt.Errorf("%s: invalid address %q, must be an address with prefix length such as 10.0.0.5/24", link.name, address)
+			}
+		}
+		families := map[bool]bool{}
+		for _, gateway := range link.gateways {
+			ip, err := netip.ParseAddr(gateway)
+			if err != nil {
+				return fmt.Errorf("%s: invalid gateway %q", link.name, gateway)
+			}
+			if families[ip.Is6()] {
+				return fmt.Errorf("%s: only one gateway per address family is allowed", link.name)
+			}
+			families[ip.Is6()] = true
+		}
+		for _, route := range link.routes {
+			destination, err := netip.ParsePrefix(route.destination)
+			if err != nil {
+				return fmt.Errorf("%s: invalid route destination %q", link.name, route.destination)
+			}
+			if route.gateway != "" {
+				gateway, err := netip.ParseAddr(route.gateway)
+				if err != nil || gateway.Is6() != destination.Addr().Is6() {
+					return fmt.Errorf("%s: invalid gateway %q of route %s", link.name, route.gateway, route.destination)
+				}
+			}
+		}
+		for _, server := range link.dns {
+			if _, err := netip.ParseAddr(server); err != nil {
+				return fmt.Errorf("%s: invalid DNS server %q", link.name, server)
+			}
+		}
+		for _, option := range configutil.SortedKeys(link.bondOptions) {
+			if _, ok := networkdBondOptions[option]; !ok {
+				return fmt.Errorf("%s: unsupported bond option %s, supported are %s", link.name, option, strings.Join(configutil.SortedKeys(networkdBondOptions), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Connection UUID of a link, derived from its name so that the keyfile is stable.
+func connectionUUID(name string) string {
+	sum := sha1.Sum([]byte("ignition_network " + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func isIPv6(address string) bool {
+	return strings.Contains(address, ":")
+}
+
+// Render the links as NetworkManager keyfiles.
+func networkManagerFiles(links []networkLink) []networkFile {
+	byName := map[string]networkLink{}
+	for _, link := range links {
+		byName[link.name] = link
+	}
+	var files []networkFile
+	for _, link := range links {
+		var b strings.Builder
+		fmt.Fprintf(&b, "[connection]\nid=%s\nuuid=%s\n", link.name, connectionUUID(link.name))
+		connectionType := link.kind
+		if connectionType == "ethernet" && link.macAddress != "" {
+			b.WriteString("type=ethernet\n")
+		} else {
+			fmt.Fprintf(&b, "type=%s\ninterface-name=%s\n", connectionType, link.name)
+		}
+		if link.master != "" {
+			fmt.Fprintf(&b, "master=%s\nslave-type=%s\n", link.master, byName[link.master].kind)
+		}
+		if link.macAddress != "" || link.mtu != 0 {
+			b.WriteString("\n[ethernet]\n")
+			if link.macAddress != "" {
+				fmt.Fprintf(&b, "mac-address=%s\n", strings.ToUpper(link.macAddress))
+			}
+			if link.mtu != 0 {
+				fmt.Fprintf(&b, "mtu=%d\n", link.mtu)
+			}
+		}
+		switch link.kind {
+		case "bond":
+			fmt.Fprintf(&b, "\n[bond]\nmode=%s\n", link.bondMode)
+			for _, option := range configutil.SortedKeys(link.bondOptions) {
+				fmt.Fprintf(&b, "%s=%s\n", option, link.bondOptions[option])
+			}
+		case "bridge":
+			fmt.Fprintf(&b, "\n[bridge]\nstp=%t\n", link.stp)
+		case "vlan":
+			fmt.Fprintf(&b, "\n[vlan]\nid=%d\nparent=%s\n", link.vlanID, connectionUUID(link.parent))
+		}
+		if link.master == "" {
+			writeNetworkManagerIP(&b, link, false)
+			writeNetworkManagerIP(&b, link, true)
+		}
+		files = append(files, networkFile{
+			path: "/etc/NetworkManager/system-connections/" + link.name + ".nmconnection",
+			// NetworkManager ignores keyfiles which are readable by others
+			mode:     0600,
+			contents: b.String(),
+		})
+	}
+	return files
+}
+
+func writeNetworkManagerIP(b *strings.Builder, link networkLink, ipv6 bool) {
+	section, dhcp := "ipv4", link.dhcp == dhcpIPv4 || link.dhcp == dhcpBoth
+	if ipv6 {
+		section, dhcp = "ipv6", link.dhcp == dhcpIPv6 || link.dhcp == dhcpBoth
+	}
+	var addresses, gateways, dns []string
+	var routes []networkRoute
+	for _, address := range link.addresses {
+		if isIPv6(address) == ipv6 {
+			addresses = append(addresses, address)
+		}
+	}
+	for _, gateway := range link.gateways {
+		if isIPv6(gateway) == ipv6 {
+			gateways = append(gateways, gateway)
+		}
+	}
+	for _, route := range link.routes {
+		if isIPv6(route.destination) == ipv6 {
+			routes = append(routes, route)
+		}
+	}
+	for _, server := range link.dns {
+		if isIPv6(server) == ipv6 {
+			dns = append(dns, server)
+		}
+	}
+	method := "disabled"
+	switch {
+	case dhcp:
+		method = "auto"
+	case len(addresses) > 0:
+		method = "manual"
+	}
+	fmt.Fprintf(b, "\n[%s]\nmethod=%s\n", section, method)
+	if method == "disabled" {
+		return
+	}
+	for i, address := range addresses {
+		fmt.Fprintf(b, "address%d=%s\n", i+1, address)
+	}
+	for _, gateway := range gateways {
+		fmt.Fprintf(b, "gateway=%s\n", gateway)
+	}
+	for i, route := range routes {
+		fmt.Fprintf(b, "route%d=%s", i+1, route.destination)
+		if route.gateway != "" || route.metric != 0 {
+			fmt.Fprintf(b, ",%s", route.gateway)
+		}
+		if route.metric != 0 {
+			fmt.Fprintf(b, ",%d", route.metric)
+		}
+		b.WriteString("\n")
+	}
+	if len(dns) > 0 {
+		fmt.Fprintf(b, "dns=%s;\n", strings.Join(dns, ";"))
+	}
+	// search domains apply to the connection, they are set for the first enabled family
+	if len(link.dnsSearch) > 0 && (!ipv6 || !networkManagerIPv4Enabled(link)) {
+		fmt.Fprintf(b, "dns-search=%s;\n", strings.Join(link.dnsSearch, ";"))
+	}
+}
+
+func networkManagerIPv4Enabled(link networkLink) bool {
+	if link.dhcp == dhcpIPv4 || link.dhcp == dhcpBoth {
+		return true
+	}
+	for _, address := range link.addresses {
+		if !isIPv6(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render the links as systemd-networkd .network and .netdev files. The files sort
+// before the defaults of the OS, so they take precedence.
+func networkdFiles(links []networkLink) []networkFile {
+	vlans := map[string][]string{}
+	for _, link := range links {
+		if link.kind == "vlan" {
+			vlans[link.parent] = append(vlans[link.parent], link.name)
+		}
+	}
+	var files []networkFile
+	for _, link := range links {
+		if link.kind != "ethernet" {
+			var b strings.Builder
+			fmt.Fprintf(&b, "[NetDev]\nName=%s\nKind=%s\n", link.name, link.kind)
+			switch link.kind {
+			case "bond":
+				fmt.Fprintf(&b, "\n[Bond]\nMode=%s\n", link.bondMode)
+				for _, option := range configutil.SortedKeys(link.bondOptions) {
+					value := link.bondOptions[option]
+					if networkdBondMilliseconds[option] {
+						value += "ms"
+					}
+					if networkdBondLists[option] {
+						value = strings.Join(strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }), " ")
+					}
+					fmt.Fprintf(&b, "%s=%s\n", networkdBondOptions[option], value)
+				}
+			case "bridge":
+				fmt.Fprintf(&b, "\n[Bridge]\nSTP=%s\n", yesNo(link.stp))
+			case "vlan":
+				fmt.Fprintf(&b, "\n[VLAN]\nId=%d\n", link.vlanID)
+			}
+			files = append(files, networkFile{
+				path:     "/etc/systemd/network/10-" + link.name + ".netdev",
+				mode:     0644,
+				contents: b.String(),
+			})
+		}
+
+		var b strings.Builder
+		b.WriteString("[Match]\n")
+		if link.macAddress != "" {
+			fmt.Fprintf(&b, "MACAddress=%s\n", strings.ToLower(link.macAddress))
+		} else {
+			fmt.Fprintf(&b, "Name=%s\n", link.name)
+		}
+		if link.mtu != 0 {
+			fmt.Fprintf(&b, "\n[Link]\nMTUBytes=%d\n", link.mtu)
+		}
+		b.WriteString("\n[Network]\n")
+		switch link.dhcp {
+		case dhcpIPv4, dhcpIPv6:
+			fmt.Fprintf(&b, "DHCP=%s\n", link.dhcp)
+		case dhcpBoth:
+			b.WriteString("DHCP=yes\n")
+		}
+		for _, address := range link.addresses {
+			fmt.Fprintf(&b, "Address=%s\n", address)
+		}
+		for _, gateway := range link.gateways {
+			fmt.Fprintf(&b, "Gateway=%s\n", gateway)
+		}
+		for _, server := range link.dns {
+			fmt.Fprintf(&b, "DNS=%s\n", server)
+		}
+		if len(link.dnsSearch) > 0 {
+			fmt.Fprintf(&b, "Domains=%s\n", strings.Join(link.dnsSearch, " "))
+		}
+		if link.master != "" {
+			kind := "Bond"
+			for _, master := range links {
+				if master.name == link.master && master.kind == "bridge" {
+					kind = "Bridge"
+				}
+			}
+			fmt.Fprintf(&b, "%s=%s\n", kind, link.master)
+		}
+		for _, vlan := range vlans[link.name] {
+			fmt.Fprintf(&b, "VLAN=%s\n", vlan)
+		}
+		for _, route := range link.routes {
+			fmt.Fprintf(&b, "\n[Route]\nDestination=%s\n", route.destination)
+			if route.gateway != "" {
+				fmt.Fprintf(&b, "Gateway=%s\n", route.gateway)
+			}
+			if route.metric != 0 {
+				fmt.Fprintf(&b, "Metric=%d\n", route.metric)
+			}
+		}
+		files = append(files, networkFile{
+			path:     "/etc/systemd/network/10-" + link.name + ".network",
+			mode:     0644,
+			contents: b.String(),
+		})
+	}
+	return files
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func testNetworkLinks() []networkLink {
+	return []networkLink{
+		{kind: "ethernet", name: "eno1", master: "bond0", dhcp: dhcpNone},
+		{kind: "ethernet", name: "eno2", macAddress: "52:54:00:AA:BB:CC", master: "bond0", dhcp: dhcpNone},
+		{kind: "bond", name: "bond0", dhcp: dhcpNone, bondMode: "802.3ad", bondOptions: map[string]string{"miimon": "100"}},
+		{
+			kind: "vlan", name: "bond0.10", vlanID: 10, parent: "bond0", dhcp: dhcpIPv6,
+			addresses: []string{"10.0.0.5/24"}, gateways: []string{"10.0.0.1"}, dns: []string{"10.0.0.2"},
+			routes: []networkRoute{{destination: "10.1.0.0/16", gateway: "10.0.0.254", metric: 50}},
+		},
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	if err := validateNetwork(testNetworkLinks()); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]func(links []networkLink){
+		"eno1 is defined more than once":           func(links []networkLink) { links[1].name = "eno1" },
+		"master bond1 is not a bond or bridge":     func(links []networkLink) { links[0].master = "bond1" },
+		"members of bond0 cannot have addresses":   func(links []networkLink) { links[0].addresses = []string{"10.0.0.6/24"} },
+		"parent eno3 is not an interface":          func(links []networkLink) { links[3].parent = "eno3" },
+		`invalid address "10.0.0.5"`:               func(links []networkLink) { links[3].addresses = []string{"10.0.0.5"} },
+		"only one gateway per address family":      func(links []networkLink) { links[3].gateways = []string{"10.0.0.1", "10.0.0.3"} },
+		`invalid gateway "fd00::1" of route`:       func(links []networkLink) { links[3].routes[0].gateway = "fd00::1" },
+		"unsupported bond option primary":          func(links []networkLink) { links[2].bondOptions["primary"] = "eno1" },
+		`bond0.10: invalid DNS server "localhost"`: func(links []networkLink) { links[3].dns = []string{"localhost"} },
+	}
+	for message, modify := range tests {
+		links := testNetworkLinks()
+		modify(links)
+		if err := validateNetwork(links); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("expected error %q, got %v", message, err)
+		}
+	}
+}
+
+func TestNetworkFiles(t *testing.T) {
+	contents := map[string]string{}
+	for _, file := range append(networkManagerFiles(testNetworkLinks()), networkdFiles(testNetworkLinks())...) {
+		contents[file.path] = file.contents
+	}
+	expected := map[string][]string{
+		"/etc/NetworkManager/system-connections/eno2.nmconnection":     {"type=ethernet\nmaster=bond0\nslave-type=bond\n", "mac-address=52:54:00:AA:BB:CC"},
+		"/etc/NetworkManager/system-connections/bond0.nmconnection":    {"[bond]\nmode=802.3ad\nmiimon=100\n", "[ipv4]\nmethod=disabled\n"},
+		"/etc/NetworkManager/system-connections/bond0.10.nmconnection": {"parent=" + connectionUUID("bond0"), "[ipv4]\nmethod=manual\naddress1=10.0.0.5/24\ngateway=10.0.0.1\nroute1=10.1.0.0/16,10.0.0.254,50\ndns=10.0.0.2;\n", "[ipv6]\nmethod=auto\n"},
+		"/etc/systemd/network/10-eno2.network":                         {"MACAddress=52:54:00:aa:bb:cc", "Bond=bond0"},
+		"/etc/systemd/network/10-bond0.netdev":                         {"Kind=bond", "Mode=802.3ad\nMIIMonitorSec=100ms\n"},
+		"/etc/systemd/network/10-bond0.network":                        {"VLAN=bond0.10"},
+		"/etc/systemd/network/10-bond0.10.network":                     {"DHCP=ipv6\nAddress=10.0.0.5/24\nGateway=10.0.0.1\n", "[Route]\nDestination=10.1.0.0/16\nGateway=10.0.0.254\nMetric=50\n"},
+	}
+	for path, parts := range expected {
+		for _, part := range parts {
+			if !strings.Contains(contents[path], part) {
+				t.Errorf("%s: expected %q in\n%s", path, part, contents[path])
+			}
+		}
+	}
+	links := testNetworkLinks()
+	links[2].bondOptions = map[string]string{"arp_interval": "100", "arp_ip_target": "10.0.0.1,10.0.0.2"}
+	for _, file := range append(networkManagerFiles(links), networkdFiles(links)...) {
+		contents[file.path] = file.contents
+	}
+	for path, part := range map[string]string{
+		"/etc/NetworkManager/system-connections/bond0.nmconnection": "arp_interval=100\narp_ip_target=10.0.0.1,10.0.0.2\n",
+		"/etc/systemd/network/10-bond0.netdev":                      "ARPIntervalSec=100ms\nARPIPTargets=10.0.0.1 10.0.0.2\n",
+	} {
+		if !strings.Contains(contents[path], part) {
+			t.Errorf("%s: expected %q in\n%s", path, part, contents[path])
+		}
+	}
+
+	if uuid := connectionUUID("bond0"); uuid != connectionUUID("bond0") || len(uuid) != 36 || uuid[14] != '5' {
+		t.Errorf("unexpected connection UUID %s", uuid)
+	}
+}
@@ -1,14 +1,62 @@
 package internal
 
 import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
 )
 
 // Provider returns a config transpiler Provider.
 func Provider() *schema.Provider {
 	return &schema.Provider{
+		Schema: map[string]*schema.Schema{
+			"rule": ruleSchema(),
+		},
 		DataSourcesMap: map[string]*schema.Resource{
-			"ignition_config": datasourceConfig(),
+			"ignition_assert":         datasourceAssert(),
+			"ignition_config":         datasourceConfig(),
+			"ignition_config_diff":    datasourceConfigDiff(),
+			"ignition_config_inspect": datasourceConfigInspect(),
+			"ignition_config_set":     datasourceConfigSet(),
+			"ignition_directory":      datasourceDirectory(),
+			"ignition_disk":           datasourceDisk(),
+			"ignition_file":           datasourceFile(),
+			"ignition_filesystem":     datasourceFilesystem(),
+			"ignition_group":          datasourceGroup(),
+			"ignition_link":           datasourceLink(),
+			"ignition_luks":           datasourceLuks(),
+			"ignition_network":        datasourceNetwork(),
+			"ignition_password_hash":  datasourcePasswordHash(),
+			"ignition_raid":           datasourceRaid(),
+			"ignition_systemd_dropin": datasourceSystemdDropin(),
+			"ignition_systemd_unit":   datasourceSystemdUnit(),
+			"ignition_to_butane":      datasourceToButane(),
+			"ignition_user":           datasourceUser(),
 		},
+		ConfigureContextFunc: providerConfigure,
+	}
+}
+
+// Settings of the provider block, passed to data sources as meta.
+type providerMeta struct {
+	rules []render.Rule
+}
+
+func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}, diag.Diagnostics) {
+	rules, err := expandRules(d.Get("rule").([]interface{}))
+	if err != nil {
+		return nil, diag.FromErr(err)
+	}
+	return &providerMeta{rules: rules}, nil
+}
+
+// Returns the rules of the provider block, if the provider was configured.
+func providerRules(meta interface{}) []render.Rule {
+	if m, ok := meta.(*providerMeta); ok && m != nil {
+		return m.rules
 	}
+	return nil
 }
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+
+	"github.com/e-breuninger/terraform-provider-ignition/pkg/render"
+)
+
+func ruleSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"name": {
+					Type:        schema.TypeString,
+					Required:    true,
+					Description: "name of a built-in rule, or of a custom rule if expression is set",
+				},
+				"expression": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: "CEL expression evaluating to true if the merged config `config` complies",
+				},
+				"message": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"severity": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      string(render.SeverityError),
+					ValidateFunc: validation.StringInSlice([]string{string(render.SeverityError), string(render.SeverityWarning)}, false),
+				},
+				"allowed_hosts": {
+					Type:        schema.TypeList,
+					Optional:    true,
+					Elem:        &schema.Schema{Type: schema.TypeString},
+					Description: "hosts remote sources may be fetched from, used by the allowed_source_hosts rule",
+				},
+			},
+		},
+	}
+}
+
+// Build rules from a rule block list. Custom rule expressions are compiled here,
+// so syntax errors are reported before any config is rendered.
+func expandRules(rawRules []interface{}) ([]render.Rule, error) {
+	rules := make([]render.Rule, 0, len(rawRules))
+	for _, raw := range rawRules {
+		attributes := raw.(map[string]interface{})
+		rule := render.Rule{
+			Name:       attributes["name"].(string),
+			Expression: attributes["expression"].(string),
+			Severity:   render.Severity(attributes["severity"].(string)),
+			Message:    attributes["message"].(string),
+		}
+		for _, host := range attributes["allowed_hosts"].([]interface{}) {
+			if host != nil {
+				rule.AllowedHosts = append(rule.AllowedHosts, host.(string))
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := render.ValidateRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Rules of a data source replace provider rules of the same name.
+func mergeRules(providerRules, datasourceRules []render.Rule) []render.Rule {
+	overridden := map[string]bool{}
+	for _, rule := range datasourceRules {
+		overridden[rule.Name] = true
+	}
+	var rules []render.Rule
+	for _, rule := range providerRules {
+		if !overridden[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	return append(rules, datasourceRules...)
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	r "github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const rulesConfig = `
+---
+variant: fcos
+version: 1.5.0
+passwd:
+  users:
+    - name: core
+      password_hash: $y$j9T$F5Jx5fExrKuPp53xLKQ..1$tnSYvahCwPBHKZUspmcxMfb0.WiB9W.zEaKlOBL35rC
+storage:
+  files:
+    - path: /etc/motd
+      mode: 0666
+      contents:
+        inline: hello
+    - path: /opt/tool
+      contents:
+        source: http://example.com/tool
+`
+
+const rulesBuiltinResource = `
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name = "no_core_password"
+  }
+}
+`
+
+const rulesHostsResource = `
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name          = "allowed_source_hosts"
+    allowed_hosts = ["mirror.example.org"]
+  }
+}
+`
+
+const rulesWarningResource = `
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name     = "no_world_writable"
+    severity = "warning"
+  }
+  rule {
+    name       = "max_files"
+    expression = "size(config.storage.files) <= 2"
+  }
+}
+`
+
+const rulesExpressionResource = `
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name       = "motd_read_only"
+    expression = "config.storage.files.all(f, f.path != '/etc/motd' || f.mode == 420)"
+    message    = "motd must have mode 0644"
+  }
+}
+`
+
+const rulesProviderResource = `
+provider "ignition" {
+  rule {
+    name = "https_sources"
+  }
+}
+
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+}
+`
+
+const rulesOverrideResource = `
+provider "ignition" {
+  rule {
+    name = "https_sources"
+  }
+}
+
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name     = "https_sources"
+    severity = "warning"
+  }
+}
+`
+
+const rulesUnknownResource = `
+data "ignition_config" "rules" {
+  content = <<EOT` + rulesConfig + `EOT
+  rule {
+    name = "no_such_rule"
+  }
+}
+`
+
+func TestRules(t *testing.T) {
+	r.UnitTest(t, r.TestCase{
+		Providers: testProviders,
+		Steps: []r.TestStep{
+			{
+				Config:      rulesBuiltinResource,
+				ExpectError: regexp.MustCompile(`rule no_core_password violated`),
+			},
+			{
+				Config:      rulesHostsResource,
+				ExpectError: regexp.MustCompile(`file /opt/tool is fetched from a host which is not allowed`),
+			},
+			{
+				Config: rulesWarningResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.rules", "rendered"),
+				),
+			},
+			{
+				Config:      rulesExpressionResource,
+				ExpectError: regexp.MustCompile(`rule motd_read_only violated: motd must have mode 0644`),
+			},
+			{
+				Config:      rulesProviderResource,
+				ExpectError: regexp.MustCompile(`file /opt/tool is not fetched over https`),
+			},
+			{
+				Config: rulesOverrideResource,
+				Check: r.ComposeTestCheckFunc(
+					r.TestCheckResourceAttrSet("data.ignition_config.rules", "rendered"),
+				),
+			},
+			{
+				Config:      rulesUnknownResource,
+				ExpectError: regexp.MustCompile(`rule no_such_rule: unknown built-in rule`),
+			},
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Minimum size of RSA keys, smaller keys are rejected by current OpenSSH versions.
+const minRSABits = 2048
+
+// A parsed SSH public key of an authorized_keys line.
+type sshKey struct {
+	algorithm   string
+	bits        int
+	fingerprint string
+	comment     string
+}
+
+// Parse and validate an authorized_keys line. Options such as from="..." are allowed,
+// DSA keys and RSA keys below minRSABits are rejected as OpenSSH refuses them.
+func parseAuthorizedKey(line string) (sshKey, error) {
+	publicKey, comment, _, rest, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return sshKey{}, fmt.Errorf("invalid SSH public key %q: %v", abbreviateKey(line), err)
+	}
+	if len(strings.TrimSpace(string(rest))) > 0 {
+		return sshKey{}, fmt.Errorf("invalid SSH public key %q: only one key per entry is allowed", abbreviateKey(line))
+	}
+	key := sshKey{
+		algorithm:   publicKey.Type(),
+		fingerprint: ssh.FingerprintSHA256(publicKey),
+		comment:     comment,
+	}
+	switch key.algorithm {
+	case ssh.KeyAlgoDSA:
+		return key, fmt.Errorf("SSH key %s: %s keys are deprecated and disabled by OpenSSH", key.fingerprint, key.algorithm)
+	case ssh.KeyAlgoED25519, ssh.KeyAlgoSKED25519:
+		key.bits = 256
+	case ssh.KeyAlgoSKECDSA256:
+		key.bits = 256
+	default:
+		cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return key, fmt.Errorf("SSH key %s: unsupported key type %s", key.fingerprint, key.algorithm)
+		}
+		switch k := cryptoKey.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			key.bits = k.N.BitLen()
+			if key.bits < minRSABits {
+				return key, fmt.Errorf("SSH key %s: RSA keys must have at least %d bits, got %d", key.fingerprint, minRSABits, key.bits)
+			}
+		case *ecdsa.PublicKey:
+			key.bits = k.Curve.Params().BitSize
+		}
+	}
+	return key, nil
+}
+
+// Shorten a key for error messages, the type and the start of the key identify it.
+func abbreviateKey(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) > 40 {
+		return line[:40] + "..."
+	}
+	return line
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func authorizedKey(t *testing.T, key interface{}, comment string) string {
+	publicKey, err := ssh.NewPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))) + " " + comment
+}
+
+func TestParseAuthorizedKey(t *testing.T) {
+	ed25519Key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGR+R78UWbJfP9oemfHApBkAdUFxrYh4Aryi3v5R0ZnJ alice@example.com"
+	key, err := parseAuthorizedKey(ed25519Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := sshKey{
+		algorithm:   "ssh-ed25519",
+		bits:        256,
+		fingerprint: "SHA256:L08QmLa/+uJhXStDo1MyFVVeBxUDMxbs0OcaX5yRBto",
+		comment:     "alice@example.com",
+	}
+	if key != expected {
+		t.Errorf("expected %+v, got %+v", expected, key)
+	}
+	if _, err := parseAuthorizedKey(`from="10.0.0.0/8" ` + ed25519Key); err != nil {
+		t.Errorf("key with options: %v", err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := parseAuthorizedKey(authorizedKey(t, &ecdsaKey.PublicKey, "ecdsa")); err != nil || key.bits != 384 {
+		t.Errorf("ECDSA key: expected 384 bits, got %+v, %v", key, err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsaKey := "ssh-dss AAAAB3NzaC1kc3MAAACBAIOsGzRscBdICpwYcOjUyOoH74VORih12LfnPFnRGoJDMA8/rD7xfMh68DeiuNYkauDPEw8VsYnNVyjDeh4R/lrP9rH7gdkZSEzqcOWfFZTXxMbkXeQyxSgT6NAF2gmVGqVuN4tkXLn7Ax4wXI9az8KFI+rlSvmSojG5Kgjwq0GbAAAAFQCkoP/c21/+LVbOaJCYkCqjZGe+ywAAAIB+14WcpaxYFIrT4U/5nGWrr4DRby2yfSGUVmdmeTlAel2YPiEwyNXCrYA1oVVeTf4BXWGKXk+sw1hPXXozN2vJQyougmbnsowJRuJIKmTME6D7Qu4r8Hr57lR4On22GLpcMlO+Z52BcyhqmJtirqG/6b/Dq6Ub/5b11mD4hb6IngAAAIAqSzZqSTAh82Fm/LFA5ijQcuSTyCZG2zL0sz+L+73D7zclHVUkdEwX+//ExoC3bjaKa11AuzPXXmb7MqeYPgEunqUfy596uye0oDmOfxo1H6Dlmzt0lxXKiyNBYlYo8nLI2cHUb9Zu+WTsIKQDXZZlfO5Yb4gIRDkCwvNVv6Ie4Q== old"
+	errors := map[string]string{
+		authorizedKey(t, &rsaKey.PublicKey, "short"):       "RSA keys must have at least 2048 bits, got 1024",
+		strings.Replace(ed25519Key, "AAAAC3", "AAAAC4", 1): "invalid SSH public key",
+		ed25519Key + "\n" + ed25519Key:                     "only one key per entry",
+		dsaKey:                                             "ssh-dss keys are deprecated",
+	}
+	for line, message := range errors {
+		if _, err := parseAuthorizedKey(line); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: expected error %q, got %v", abbreviateKey(line), message, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Tang thumbprints are base64url JWK thumbprints of the signing key, of SHA-1 as
+// created by older clevis versions or SHA-256.
+var tangThumbprintPattern = regexp.MustCompile(`^([A-Za-z0-9_-]{27}|[A-Za-z0-9_-]{43})$`)
+
+// Members of a JWK which make up its thumbprint (RFC 7638), by key type.
+var jwkThumbprintMembers = map[string][]string{
+	"EC":  {"crv", "kty", "x", "y"},
+	"RSA": {"e", "kty", "n"},
+	"OKP": {"crv", "kty", "x"},
+}
+
+// Check a Tang thumbprint, and if an advertisement is given, that it contains a
+// signing key with the thumbprint. The advertisement is the JWS served at /adv.
+func validateTang(thumbprint, advertisement string) error {
+	if !tangThumbprintPattern.MatchString(thumbprint) {
+		return fmt.Errorf("invalid thumbprint %q: must be a base64url SHA-1 or SHA-256 JWK thumbprint as printed by tang-show-keys", thumbprint)
+	}
+	if advertisement == "" {
+		return nil
+	}
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(advertisement), &jws); err != nil || jws.Payload == "" {
+		return fmt.Errorf("invalid advertisement: not a JWS as served by the /adv endpoint of tang")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid advertisement payload: %v", err)
+	}
+	var keySet struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(payload, &keySet); err != nil {
+		return fmt.Errorf("invalid advertisement payload: %v", err)
+	}
+	var thumbprints []string
+	for _, key := range keySet.Keys {
+		if !jwkCanVerify(key) {
+			continue
+		}
+		sha1Thumbprint, sha256Thumbprint, err := jwkThumbprints(key)
+		if err != nil {
+			return fmt.Errorf("invalid advertisement: %v", err)
+		}
+		if thumbprint == sha1Thumbprint || thumbprint == sha256Thumbprint {
+			return nil
+		}
+		thumbprints = append(thumbprints, sha256Thumbprint)
+	}
+	return fmt.Errorf("thumbprint %s does not match a signing key of the advertisement, which has %v", thumbprint, thumbprints)
+}
+
+// Keys without key_ops may be used for any operation.
+func jwkCanVerify(key map[string]interface{}) bool {
+	ops, ok := key["key_ops"].([]interface{})
+	if !ok {
+		return true
+	}
+	for _, op := range ops {
+		if op == "verify" {
+			return true
+		}
+	}
+	return false
+}
+
+// SHA-1 and SHA-256 thumbprints of a JWK, computed over its required members in
+// lexicographic order, which is the order json.Marshal uses for maps.
+func jwkThumbprints(key map[string]interface{}) (string, string, error) {
+	kty, _ := key["kty"].(string)
+	names, ok := jwkThumbprintMembers[kty]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported key type %q", kty)
+	}
+	members := map[string]string{}
+	for _, name := range names {
+		value, ok := key[name].(string)
+		if !ok {
+			return "", "", fmt.Errorf("%s key without %s", kty, name)
+		}
+		members[name] = value
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return "", "", err
+	}
+	sum1 := sha1.Sum(canonical)
+	sum256 := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum1[:]), base64.RawURLEncoding.EncodeToString(sum256[:]), nil
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"os"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 
 	"github.com/e-breuninger/terraform-provider-ignition/internal"
 )
 
 func main() {
+	// Terraform launches the plugin without arguments and with the magic cookie set
+	if len(os.Args) > 1 && os.Getenv(plugin.Handshake.MagicCookieKey) == "" {
+		os.Exit(internal.RunCommand(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: internal.Provider,
 	})
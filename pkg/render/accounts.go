@@ -0,0 +1,241 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+	"github.com/e-breuninger/terraform-provider-ignition/internal/crypt"
+)
+
+// Users and groups a variant ships in its base image.
+type variantAccounts struct {
+	users  map[string]bool
+	groups map[string]bool
+}
+
+var variantAccountRules = map[string]variantAccounts{
+	"fcos": {
+		users: stringSet(
+			"root", "bin", "daemon", "adm", "lp", "sync", "shutdown", "halt", "mail", "operator",
+			"games", "ftp", "nobody", "core", "dbus", "systemd-network", "systemd-resolve",
+			"systemd-timesync", "systemd-oom", "systemd-coredump", "tss", "polkitd", "sshd",
+			"chrony", "dnsmasq", "rpc", "rpcuser", "sssd", "unbound", "zincati", "clevis",
+		),
+		groups: stringSet(
+			"root", "bin", "daemon", "sys", "adm", "tty", "disk", "lp", "mem", "kmem", "wheel",
+			"cdrom", "mail", "man", "dialout", "floppy", "games", "tape", "video", "ftp", "lock",
+			"audio", "users", "nobody", "utmp", "utempter", "input", "kvm", "render", "sgx",
+			"hugetlbfs", "sudo", "core", "docker", "dbus", "systemd-journal", "systemd-network",
+			"systemd-resolve", "systemd-timesync", "systemd-oom", "systemd-coredump", "tss",
+			"polkitd", "sshd", "ssh_keys", "chrony", "dnsmasq", "rpc", "rpcuser", "sssd",
+			"unbound", "zincati", "clevis", "printadmin",
+		),
+	},
+	"flatcar": {
+		users: stringSet(
+			"root", "bin", "daemon", "adm", "lp", "sync", "shutdown", "halt", "news", "uucp",
+			"operator", "man", "nobody", "portage", "core", "sshd", "messagebus", "polkitd",
+			"etcd", "fleet", "tss", "tcsd", "dnsmasq", "ntp", "chrony", "systemd-network",
+			"systemd-resolve", "systemd-timesync", "systemd-coredump", "systemd-journal-remote",
+		),
+		groups: stringSet(
+			"root", "bin", "daemon", "sys", "adm", "tty", "disk", "lp", "mem", "kmem", "wheel",
+			"floppy", "mail", "news", "uucp", "man", "cron", "console", "audio", "cdrom",
+			"dialout", "tape", "video", "cdrw", "usb", "users", "nobody", "nogroup", "portage",
+			"utmp", "lock", "input", "kvm", "render", "tun", "sgx", "sudo", "core", "docker",
+			"sshd", "systemd-journal", "systemd-network", "systemd-resolve", "systemd-timesync",
+			"systemd-coredump", "systemd-journal-remote", "messagebus", "polkitd", "etcd", "fleet",
+			"locksmith", "rkt", "rkt-admin", "tss", "dnsmasq", "ntp",
+		),
+	},
+}
+
+// Users and groups available on a machine provisioned with a config.
+type accounts struct {
+	users  map[string]bool
+	groups map[string]bool
+}
+
+// Collect the accounts of a variant, of passwd and of inline sysusers.d files in a config.
+func configAccounts(config types_v3_4.Config, variant string) accounts {
+	builtin := variantAccountRules[variant]
+	result := accounts{users: map[string]bool{}, groups: map[string]bool{}}
+	for name := range builtin.users {
+		result.users[name] = true
+	}
+	for name := range builtin.groups {
+		result.groups[name] = true
+	}
+	for _, group := range config.Passwd.Groups {
+		result.groups[group.Name] = true
+	}
+	for _, user := range config.Passwd.Users {
+		result.users[user.Name] = true
+		// useradd creates a group of the same name unless told otherwise
+		if !configutil.BoolValue(user.NoUserGroup) {
+			result.groups[user.Name] = true
+		}
+	}
+	for _, file := range config.Storage.Files {
+		if path.Dir(file.Path) != "/etc/sysusers.d" || path.Ext(file.Path) != ".conf" {
+			continue
+		}
+		contents, ok, err := fileContents(file)
+		if err != nil || !ok {
+			continue
+		}
+		for _, line := range strings.Split(string(contents), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "u", "u!":
+				result.users[fields[1]] = true
+				result.groups[fields[1]] = true
+			case "g":
+				result.groups[fields[1]] = true
+			}
+		}
+	}
+	return result
+}
+
+// Sections of unit types which run processes as a user.
+var execSections = map[string]string{
+	"service": "Service",
+	"socket":  "Socket",
+	"mount":   "Mount",
+	"swap":    "Swap",
+}
+
+// Returns true if an account reference can be resolved. Numeric IDs and systemd
+// specifiers such as %i cannot be checked and are accepted.
+func resolvable(name string, known map[string]bool) bool {
+	if _, err := strconv.Atoi(name); err == nil {
+		return true
+	}
+	return name == "" || strings.Contains(name, "%") || known[name]
+}
+
+// Check that all users and groups referenced by files, directories, links, units and
+// passwd exist. Unknown variants are not checked, as their built-in accounts are unknown.
+func checkAccounts(config types_v3_4.Config, variant string, inputs *conflictTracker) Diagnostics {
+	var diags Diagnostics
+	if _, ok := variantAccountRules[variant]; !ok {
+		return diags
+	}
+	known := configAccounts(config, variant)
+	report := func(severity Severity, name, key, format string, args ...interface{}) {
+		if input := inputs.input(key); input != "" {
+			name = fmt.Sprintf("%s (%s)", name, input)
+		}
+		diags = append(diags, Diagnostic{
+			Severity: severity,
+			Summary:  "unknown account",
+			Detail:   fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)),
+		})
+	}
+	checkUser := func(severity Severity, name, key, user string) {
+		if !resolvable(user, known.users) {
+			report(severity, name, key, "user %s is neither created in passwd.users nor built into %s", user, variant)
+		}
+	}
+	checkGroup := func(severity Severity, name, key, group string) {
+		if !resolvable(group, known.groups) {
+			report(severity, name, key, "group %s is neither created in passwd.groups nor built into %s", group, variant)
+		}
+	}
+
+	// Ignition fails to create nodes owned by unknown accounts
+	checkNode := func(kind string, node types_v3_4.Node) {
+		name := fmt.Sprintf("%s %s", kind, node.Path)
+		checkUser(SeverityError, name, "path "+node.Path, configutil.StringValue(node.User.Name))
+		checkGroup(SeverityError, name, "path "+node.Path, configutil.StringValue(node.Group.Name))
+	}
+	for _, file := range config.Storage.Files {
+		checkNode("file", file.Node)
+	}
+	for _, directory := range config.Storage.Directories {
+		checkNode("directory", directory.Node)
+	}
+	for _, link := range config.Storage.Links {
+		checkNode("link", link.Node)
+	}
+
+	// useradd fails for unknown groups
+	for _, user := range config.Passwd.Users {
+		name := "user " + user.Name
+		checkGroup(SeverityError, name, name, configutil.StringValue(user.PrimaryGroup))
+		for _, group := range user.Groups {
+			checkGroup(SeverityError, name, name, string(group))
+		}
+	}
+
+	// units with unknown accounts fail to start
+	for _, unit := range config.Systemd.Units {
+		var files []unitFile
+		if unit.Contents != nil {
+			file, _ := parseUnit(*unit.Contents)
+			files = append(files, file)
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents != nil {
+				file, _ := parseUnit(*dropin.Contents)
+				files = append(files, file)
+			}
+		}
+		section, ok := execSections[unitType(unit.Name)]
+		if !ok {
+			continue
+		}
+		if dynamic := directiveValue(files, section, "DynamicUser"); dynamic == "yes" || dynamic == "true" {
+			continue
+		}
+		name := "unit " + unit.Name
+		checkUser(SeverityWarning, name, name, directiveValue(files, section, "User"))
+		checkGroup(SeverityWarning, name, name, directiveValue(files, section, "Group"))
+		for _, groups := range directiveValues(files, section, "SupplementaryGroups") {
+			for _, group := range strings.Fields(groups) {
+				checkGroup(SeverityWarning, name, name, group)
+			}
+		}
+		if section == "Socket" {
+			checkUser(SeverityWarning, name, name, directiveValue(files, section, "SocketUser"))
+			checkGroup(SeverityWarning, name, name, directiveValue(files, section, "SocketGroup"))
+		}
+	}
+	return diags
+}
+
+// Check that the password hashes of passwd.users are crypt hashes of a supported
+// algorithm, which catches plaintext passwords and truncated hashes. Outdated
+// algorithms are reported as warnings.
+func checkPasswordHashes(config types_v3_4.Config, inputs *conflictTracker) Diagnostics {
+	var diags Diagnostics
+	for _, user := range config.Passwd.Users {
+		hash := configutil.StringValue(user.PasswordHash)
+		if hash == "" {
+			continue
+		}
+		name := "user " + user.Name
+		if input := inputs.input(name); input != "" {
+			name = fmt.Sprintf("%s (%s)", name, input)
+		}
+		problem, weak := crypt.HashProblem(hash)
+		if problem == "" {
+			continue
+		}
+		d := Diagnostic{Severity: SeverityError, Summary: "invalid password hash", Detail: fmt.Sprintf("%s: %s", name, problem)}
+		if weak {
+			d.Severity, d.Summary = SeverityWarning, "weak password hash"
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
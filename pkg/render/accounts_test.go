@@ -0,0 +1,93 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestCheckAccounts(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"passwd": {
+			"users": [
+				{"name": "app", "groups": ["wheel", "builders"]},
+				{"name": "svc", "noUserGroup": true, "primaryGroup": "svc"}
+			],
+			"groups": [{"name": "ops"}]
+		},
+		"storage": {
+			"files": [
+				{"path": "/etc/app.conf", "user": {"name": "app"}, "group": {"name": "ops"}},
+				{"path": "/etc/db.conf", "user": {"name": "postgres"}, "group": {"id": 26}},
+				{"path": "/etc/sysusers.d/web.conf", "contents": {"source": "data:,u%20web%20-%20%22Web%22%0Ag%20www%0A"}}
+			],
+			"directories": [{"path": "/var/lib/web", "user": {"name": "web"}, "group": {"name": "www"}}],
+			"links": [{"path": "/etc/app.link", "target": "/etc/app.conf", "group": {"name": "staff"}}]
+		},
+		"systemd": {
+			"units": [
+				{"name": "app.service", "contents": "[Service]\nExecStart=/bin/app\nUser=app\nSupplementaryGroups=ops audit\n"},
+				{"name": "dyn.service", "contents": "[Service]\nExecStart=/bin/dyn\nDynamicUser=yes\nUser=dyn\n"},
+				{"name": "tpl@.service", "contents": "[Service]\nExecStart=/bin/tpl\nUser=%i\n"},
+				{"name": "app.socket", "contents": "[Socket]\nListenStream=/run/app.sock\nSocketGroup=sockets\n"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := newConflictTracker()
+
+	expected := []Diagnostic{
+		{Severity: SeverityError, Detail: "file /etc/db.conf: user postgres is neither created in passwd.users nor built into fcos"},
+		{Severity: SeverityError, Detail: "link /etc/app.link: group staff is neither created in passwd.groups nor built into fcos"},
+		{Severity: SeverityError, Detail: "user app: group builders is neither created in passwd.groups nor built into fcos"},
+		{Severity: SeverityError, Detail: "user svc: group svc is neither created in passwd.groups nor built into fcos"},
+		{Severity: SeverityWarning, Detail: "unit app.service: group audit is neither created in passwd.groups nor built into fcos"},
+		{Severity: SeverityWarning, Detail: "unit app.socket: group sockets is neither created in passwd.groups nor built into fcos"},
+	}
+	diags := checkAccounts(config, "fcos", inputs)
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i := range expected {
+		if diags[i].Severity != expected[i].Severity || diags[i].Detail != expected[i].Detail {
+			t.Errorf("expected %v %q, got %v %q", expected[i].Severity, expected[i].Detail, diags[i].Severity, diags[i].Detail)
+		}
+	}
+
+	if diags := checkAccounts(config, "openshift", inputs); len(diags) != 0 {
+		t.Errorf("expected no problems for unknown variants, got %v", diags)
+	}
+}
+
+func TestCheckPasswordHashes(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"passwd": {
+			"users": [
+				{"name": "locked", "passwordHash": "!"},
+				{"name": "disabled", "passwordHash": "*"},
+				{"name": "old", "passwordHash": "$1$saltsalt$qjXMvbEw8oaL.CzflDugX/"},
+				{"name": "plain", "passwordHash": "hunter2"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Diagnostic{
+		{Severity: SeverityWarning, Detail: "user old: password_hash uses md5crypt, which is easily cracked, use yescrypt instead"},
+		{Severity: SeverityError, Detail: "user plain: password_hash is not a yescrypt, sha512crypt, sha256crypt or bcrypt hash, create one with the ignition_password_hash data source"},
+	}
+	diags := checkPasswordHashes(config, newConflictTracker())
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i := range expected {
+		if diags[i].Severity != expected[i].Severity || diags[i].Detail != expected[i].Detail {
+			t.Errorf("expected %v %q, got %v %q", expected[i].Severity, expected[i].Detail, diags[i].Severity, diags[i].Detail)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// How entries defined by more than one input are handled.
+type ConflictPolicy string
+
+const (
+	// keep the last definition silently, as Ignition merges do
+	ConflictLastWins ConflictPolicy = "last_wins"
+	// keep the last definition and warn about it
+	ConflictWarn ConflictPolicy = "warn"
+	// fail rendering
+	ConflictError ConflictPolicy = "error"
+)
+
+// Tracks which input of an ignition_config defines an entry and its fields, and detects
+// fields which more than one input sets to different values. ignition.Merge merges
+// entries with the same key field by field and silently keeps the last value of a field.
+type conflictTracker struct {
+	entries   map[string]*trackedEntry
+	conflicts []string
+}
+
+type trackedEntry struct {
+	name string
+	// input which defined the entry last, ignoring inputs which set none of its fields
+	input string
+	// input and value by field
+	fields map[string]trackedField
+}
+
+type trackedField struct {
+	input string
+	value string
+}
+
+func newConflictTracker() *conflictTracker {
+	return &conflictTracker{entries: map[string]*trackedEntry{}}
+}
+
+// Record the entries of an input, e.g. "content" or "snippet 0". An entry of another
+// kind at the same path replaces the previous one and is a conflict as a whole.
+func (t *conflictTracker) add(input string, config types_v3_4.Config) {
+	for _, entry := range configEntries(config) {
+		tracked, ok := t.entries[entry.key]
+		if !ok || tracked.name != entry.name {
+			if ok && tracked.input != input {
+				t.conflicts = append(t.conflicts, fmt.Sprintf("%s is defined in %s and %s", entry.name, tracked.input, input))
+			}
+			tracked = &trackedEntry{name: entry.name, input: input, fields: map[string]trackedField{}}
+			t.entries[entry.key] = tracked
+		}
+		for _, field := range configutil.SortedKeys(entry.fields) {
+			value := entry.fields[field]
+			if previous, ok := tracked.fields[field]; ok && previous.input != input && previous.value != value {
+				t.conflicts = append(t.conflicts, fmt.Sprintf("%s: %s is set in %s and %s", entry.name, field, previous.input, input))
+			}
+			tracked.fields[field] = trackedField{input: input, value: value}
+		}
+		if len(entry.fields) > 0 {
+			tracked.input = input
+		}
+	}
+}
+
+// Returns the input which defined an entry last, or an empty string if unknown.
+func (t *conflictTracker) input(key string) string {
+	if tracked, ok := t.entries[key]; ok {
+		return tracked.input
+	}
+	return ""
+}
+
+// Report conflicts according to the conflict policy. An empty policy is ConflictLastWins.
+func (t *conflictTracker) diagnostics(policy ConflictPolicy) Diagnostics {
+	var diags Diagnostics
+	if policy != ConflictWarn && policy != ConflictError {
+		return diags
+	}
+	severity := SeverityWarning
+	if policy == ConflictError {
+		severity = SeverityError
+	}
+	for _, conflict := range t.conflicts {
+		diags = append(diags, Diagnostic{
+			Severity: severity,
+			Summary:  "conflicting config entries",
+			Detail:   conflict,
+		})
+	}
+	return diags
+}
+
+// An entry of a config which ignition.Merge merges by key, with the scalar fields
+// the config sets. Lists such as SSH keys or appended contents are merged by Ignition
+// and never conflict.
+type configEntry struct {
+	key    string
+	name   string
+	fields entryFields
+}
+
+// Values of the fields an entry sets, by their Butane names.
+type entryFields map[string]string
+
+func (f entryFields) setString(name string, value *string) {
+	if value != nil {
+		f[name] = *value
+	}
+}
+
+func (f entryFields) setInt(name string, value *int) {
+	if value != nil {
+		f[name] = strconv.Itoa(*value)
+	}
+}
+
+func (f entryFields) setBool(name string, value *bool) {
+	if value != nil {
+		f[name] = strconv.FormatBool(*value)
+	}
+}
+
+func nodeFields(node types_v3_4.Node) entryFields {
+	fields := entryFields{}
+	fields.setBool("overwrite", node.Overwrite)
+	fields.setInt("user.id", node.User.ID)
+	fields.setString("user.name", node.User.Name)
+	fields.setInt("group.id", node.Group.ID)
+	fields.setString("group.name", node.Group.Name)
+	return fields
+}
+
+// Collect all entries of a config which ignition.Merge merges by key.
+// Files, directories and links share their key, as a path can only hold one node.
+func configEntries(config types_v3_4.Config) []configEntry {
+	var entries []configEntry
+	add := func(key, name string, fields entryFields) {
+		entries = append(entries, configEntry{key, name, fields})
+	}
+	for _, file := range config.Storage.Files {
+		fields := nodeFields(file.Node)
+		fields.setInt("mode", file.Mode)
+		if file.Contents.Source != nil {
+			fields["contents"] = configutil.StringValue(file.Contents.Compression) + " " + *file.Contents.Source
+		}
+		add("path "+file.Path, "file "+file.Path, fields)
+	}
+	for _, directory := range config.Storage.Directories {
+		fields := nodeFields(directory.Node)
+		fields.setInt("mode", directory.Mode)
+		add("path "+directory.Path, "directory "+directory.Path, fields)
+	}
+	for _, link := range config.Storage.Links {
+		fields := nodeFields(link.Node)
+		fields.setString("target", link.Target)
+		fields.setBool("hard", link.Hard)
+		add("path "+link.Path, "link "+link.Path, fields)
+	}
+	for _, disk := range config.Storage.Disks {
+		for _, partition := range disk.Partitions {
+			// number 0 means the next free partition number
+			if partition.Number != 0 {
+				name := fmt.Sprintf("partition %d on %s", partition.Number, disk.Device)
+				fields := entryFields{}
+				fields.setString("label", partition.Label)
+				fields.setInt("size_mib", partition.SizeMiB)
+				fields.setInt("start_mib", partition.StartMiB)
+				fields.setString("type_guid", partition.TypeGUID)
+				fields.setString("guid", partition.GUID)
+				fields.setBool("wipe_partition_entry", partition.WipePartitionEntry)
+				fields.setBool("should_exist", partition.ShouldExist)
+				fields.setBool("resize", partition.Resize)
+				add(name, name, fields)
+			}
+		}
+	}
+	for _, unit := range config.Systemd.Units {
+		fields := entryFields{}
+		fields.setString("contents", unit.Contents)
+		fields.setBool("enabled", unit.Enabled)
+		fields.setBool("mask", unit.Mask)
+		add("unit "+unit.Name, "unit "+unit.Name, fields)
+		for _, dropin := range unit.Dropins {
+			name := fmt.Sprintf("unit %s dropin %s", unit.Name, dropin.Name)
+			fields := entryFields{}
+			fields.setString("contents", dropin.Contents)
+			add(name, name, fields)
+		}
+	}
+	for _, user := range config.Passwd.Users {
+		fields := entryFields{}
+		fields.setString("password_hash", user.PasswordHash)
+		fields.setInt("uid", user.UID)
+		fields.setString("gecos", user.Gecos)
+		fields.setString("home_dir", user.HomeDir)
+		fields.setBool("no_create_home", user.NoCreateHome)
+		fields.setString("primary_group", user.PrimaryGroup)
+		fields.setBool("no_user_group", user.NoUserGroup)
+		fields.setBool("no_log_init", user.NoLogInit)
+		fields.setString("shell", user.Shell)
+		fields.setBool("system", user.System)
+		fields.setBool("should_exist", user.ShouldExist)
+		add("user "+user.Name, "user "+user.Name, fields)
+	}
+	for _, group := range config.Passwd.Groups {
+		fields := entryFields{}
+		fields.setInt("gid", group.Gid)
+		fields.setString("password_hash", group.PasswordHash)
+		fields.setBool("system", group.System)
+		fields.setBool("should_exist", group.ShouldExist)
+		add("group "+group.Name, "group "+group.Name, fields)
+	}
+	return entries
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestConflictTracker(t *testing.T) {
+	inputs := map[string]string{
+		"content": `{
+			"ignition": {"version": "3.4.0"},
+			"passwd": {"users": [{"name": "core", "shell": "/bin/bash"}]},
+			"storage": {
+				"disks": [{"device": "/dev/vdb", "partitions": [{"number": 1, "label": "data"}]}],
+				"files": [
+					{"path": "/etc/motd", "contents": {"source": "data:,content"}},
+					{"path": "/etc/issue", "mode": 420, "contents": {"source": "data:,issue"}}
+				]
+			},
+			"systemd": {"units": [{"name": "docker.service", "enabled": true, "contents": "[Service]\nExecStart=/bin/docker\n"}]}
+		}`,
+		// only adds to entries of the content, which Ignition merges field by field
+		"snippet 0": `{
+			"ignition": {"version": "3.4.0"},
+			"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA"]}]},
+			"storage": {"files": [{"path": "/etc/issue", "mode": 420, "user": {"name": "core"}}]},
+			"systemd": {"units": [{"name": "docker.service", "dropins": [{"name": "10-env.conf", "contents": "[Service]\nEnvironment=A=1\n"}]}]}
+		}`,
+		// overrides fields of the content
+		"snippet 1": `{
+			"ignition": {"version": "3.4.0"},
+			"passwd": {"users": [{"name": "core", "shell": "/bin/zsh"}]},
+			"storage": {
+				"disks": [{"device": "/dev/vdb", "partitions": [{"number": 1, "label": "other"}]}],
+				"directories": [{"path": "/etc/motd"}]
+			},
+			"systemd": {"units": [{"name": "docker.service", "enabled": false}]}
+		}`,
+	}
+	tracker := newConflictTracker()
+	for _, input := range []string{"content", "snippet 0", "snippet 1"} {
+		config, err := configutil.ParseRendered([]byte(inputs[input]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracker.add(input, config)
+	}
+
+	expected := []string{
+		"directory /etc/motd is defined in content and snippet 1",
+		"partition 1 on /dev/vdb: label is set in content and snippet 1",
+		"unit docker.service: enabled is set in content and snippet 1",
+		"user core: shell is set in content and snippet 1",
+	}
+	if len(tracker.conflicts) != len(expected) {
+		t.Fatalf("expected %d conflicts, got %d: %q", len(expected), len(tracker.conflicts), tracker.conflicts)
+	}
+	for i := range expected {
+		if tracker.conflicts[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], tracker.conflicts[i])
+		}
+	}
+
+	for key, input := range map[string]string{
+		"path /etc/issue":                        "snippet 0",
+		"unit docker.service dropin 10-env.conf": "snippet 0",
+		"unit docker.service":                    "snippet 1",
+		"path /etc/motd":                         "snippet 1",
+		"group core":                             "",
+	} {
+		if actual := tracker.input(key); actual != input {
+			t.Errorf("%s: expected input %q, got %q", key, input, actual)
+		}
+	}
+
+	if diags := tracker.diagnostics(ConflictLastWins); len(diags) != 0 {
+		t.Errorf("expected no diagnostics for last_wins, got %v", diags)
+	}
+	if diags := tracker.diagnostics(ConflictError); len(diags) != len(expected) || diags[0].Severity != SeverityError {
+		t.Errorf("expected errors, got %v", diags)
+	}
+}
@@ -0,0 +1,56 @@
+package render
+
+import "strings"
+
+// Severity of a diagnostic, or of the violations of a rule.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// A problem found while rendering. The detail names the input or config entry
+// the problem belongs to, if known.
+type Diagnostic struct {
+	Severity Severity
+	Summary  string
+	Detail   string
+}
+
+type Diagnostics []Diagnostic
+
+// Returns true if any diagnostic is an error.
+func (diags Diagnostics) HasError() bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func errorDiagnostics(err error) Diagnostics {
+	return Diagnostics{{Severity: SeverityError, Summary: err.Error()}}
+}
+
+// Returned by Render if an input is invalid or the merged config fails a check.
+// Diagnostics holds all problems found, including warnings found before the failure.
+type Error struct {
+	Diagnostics Diagnostics
+}
+
+func (e *Error) Error() string {
+	var messages []string
+	for _, d := range e.Diagnostics {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if d.Detail != "" {
+			messages = append(messages, d.Summary+": "+d.Detail)
+		} else {
+			messages = append(messages, d.Summary)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
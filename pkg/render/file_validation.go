@@ -0,0 +1,197 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// Checks the syntax of file contents, returning an error which starts with the line number.
+type fileValidator func(contents []byte) error
+
+var fileValidators = map[string]fileValidator{
+	"json": validateJSON,
+	"yaml": validateYAML,
+	"toml": validateTOML,
+	"ini":  validateINI,
+}
+
+// Formats of well known files, matched with path.Match.
+var pathFormats = map[string]string{
+	"/etc/containers/registries.conf":          "toml",
+	"/etc/containers/registries.conf.d/*.conf": "toml",
+	"/etc/containers/storage.conf":             "toml",
+	"/etc/containers/containers.conf":          "toml",
+	"/etc/containers/containers.conf.d/*.conf": "toml",
+	"/etc/zincati/config.d/*.toml":             "toml",
+	"/etc/docker/daemon.json":                  "json",
+	"/etc/NetworkManager/conf.d/*.conf":        "ini",
+	"/etc/NetworkManager/NetworkManager.conf":  "ini",
+	"/etc/systemd/network/*.network":           "ini",
+	"/etc/systemd/network/*.netdev":            "ini",
+	"/etc/systemd/network/*.link":              "ini",
+}
+
+var extensionFormats = map[string]string{
+	".json":         "json",
+	".yaml":         "yaml",
+	".yml":          "yaml",
+	".toml":         "toml",
+	".ini":          "ini",
+	".nmconnection": "ini",
+}
+
+// Determine the format of a file. Formats configured by the user take precedence
+// over well known paths, which take precedence over file extensions.
+// Returns an empty string for files of unknown format.
+func fileFormat(filePath string, formats map[string]string) string {
+	for _, patterns := range []map[string]string{formats, pathFormats} {
+		if format, ok := patterns[filePath]; ok {
+			return format
+		}
+		for _, pattern := range configutil.SortedKeys(patterns) {
+			if matched, _ := path.Match(pattern, filePath); matched {
+				return patterns[pattern]
+			}
+		}
+	}
+	return extensionFormats[path.Ext(filePath)]
+}
+
+// Validate the syntax of all inline files of a known format. Remote files are never
+// fetched and skipped, like files which are only appended to.
+func validateFiles(config types_v3_4.Config, formats map[string]string, inputs *conflictTracker) ([]string, error) {
+	for pattern, format := range formats {
+		if _, ok := fileValidators[format]; !ok {
+			return nil, fmt.Errorf("file_formats: unknown format %q for %s, use one of %s", format, pattern, strings.Join(fileFormatNames(), ", "))
+		}
+	}
+	var problems []string
+	for _, file := range config.Storage.Files {
+		format := fileFormat(file.Path, formats)
+		if format == "" || file.Contents.Source == nil {
+			continue
+		}
+		contents, ok, err := fileContents(file)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %v", file.Path, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := fileValidators[format](contents); err != nil {
+			name := "file " + file.Path
+			if input := inputs.input("path " + file.Path); input != "" {
+				name = fmt.Sprintf("%s (%s)", name, input)
+			}
+			problems = append(problems, fmt.Sprintf("%s: invalid %s: %v", name, format, err))
+		}
+	}
+	return problems, nil
+}
+
+// Contents of a file including appended resources. ok is false if any of them is remote.
+func fileContents(file types_v3_4.File) (contents []byte, ok bool, err error) {
+	for _, resource := range append([]types_v3_4.Resource{file.Contents}, file.Append...) {
+		decoded, ok, err := configutil.DecodeResource(resource)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		contents = append(contents, decoded...)
+	}
+	return contents, true, nil
+}
+
+func validateJSON(contents []byte) error {
+	var value interface{}
+	err := json.Unmarshal(contents, &value)
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		return fmt.Errorf("line %d: %v", lineOfOffset(contents, syntaxError.Offset), syntaxError)
+	}
+	return err
+}
+
+func lineOfOffset(contents []byte, offset int64) int {
+	if offset > int64(len(contents)) {
+		offset = int64(len(contents))
+	}
+	return bytes.Count(contents[:offset], []byte("\n")) + 1
+}
+
+func validateYAML(contents []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	}
+}
+
+func validateTOML(contents []byte) error {
+	var value interface{}
+	_, err := toml.Decode(string(contents), &value)
+	var parseError toml.ParseError
+	if errors.As(err, &parseError) {
+		return errors.New(strings.TrimPrefix(parseError.Error(), "toml: "))
+	}
+	return err
+}
+
+// Validate INI style files such as NetworkManager keyfiles: key=value assignments
+// grouped in sections, with comments starting with # or ;.
+func validateINI(contents []byte) error {
+	inSection := false
+	continued := false
+	for i, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		// a trailing backslash continues an assignment on the next line
+		wasContinued := continued
+		continued = strings.HasSuffix(line, "\\")
+		switch {
+		case wasContinued:
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				return fmt.Errorf("line %d: invalid section header %s", i+1, line)
+			}
+			inSection = true
+		case !strings.Contains(line, "="):
+			return fmt.Errorf("line %d: missing '=' in %q", i+1, line)
+		case !inSection:
+			return fmt.Errorf("line %d: assignment outside of a section", i+1)
+		default:
+			if key, _, _ := strings.Cut(line, "="); strings.TrimSpace(key) == "" {
+				return fmt.Errorf("line %d: empty key", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Names of the supported formats, for error messages.
+func fileFormatNames() []string {
+	names := make([]string, 0, len(fileValidators))
+	for name := range fileValidators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package render
+
+import "testing"
+
+func TestFileValidators(t *testing.T) {
+	cases := []struct {
+		format   string
+		contents string
+		err      string
+	}{
+		{"json", "{\"a\": 1}", ""},
+		{"json", "{\n\"a\": 1,\n}", "line 3: invalid character '}' looking for beginning of object key string"},
+		{"yaml", "a: 1\n---\nb: 2\n", ""},
+		{"yaml", "a: 1\nb: [2\n", "line 1: did not find expected ',' or ']'"},
+		{"toml", "[a]\nb = 1\n", ""},
+		{"toml", "[a]\nb = 1\nc = [\n", "line 3 (last key \"a.c\"): unexpected EOF; expected value"},
+		{"ini", "# comment\n[a]\nb=1\nc = 2 \\\n  3\n", ""},
+		{"ini", "b=1\n", "line 1: assignment outside of a section"},
+		{"ini", "[a]\nb\n", "line 2: missing '=' in \"b\""},
+	}
+	for _, c := range cases {
+		err := fileValidators[c.format]([]byte(c.contents))
+		if (err == nil && c.err != "") || (err != nil && err.Error() != c.err) {
+			t.Errorf("%s %q: expected error %q, got %v", c.format, c.contents, c.err, err)
+		}
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	formats := map[string]string{"/etc/app/*.conf": "toml", "/etc/docker/daemon.json": "yaml"}
+	cases := map[string]string{
+		"/etc/app/a.conf":                 "toml",
+		"/etc/docker/daemon.json":         "yaml",
+		"/etc/containers/registries.conf": "toml",
+		"/opt/config.yml":                 "yaml",
+		"/etc/motd":                       "",
+	}
+	for filePath, expected := range cases {
+		if format := fileFormat(filePath, formats); format != expected {
+			t.Errorf("%s: expected %q, got %q", filePath, expected, format)
+		}
+	}
+}
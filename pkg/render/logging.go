@@ -0,0 +1,68 @@
+package render
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/vcontext/report"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"gopkg.in/yaml.v3"
+)
+
+// Subsystems of the provider logger. Their level can be set separately, e.g. with
+// TF_LOG_PROVIDER_IGNITION_TRANSPILE=trace. Without a provider logger in the
+// context, e.g. outside of Terraform, nothing is logged.
+const (
+	// merging inputs and checking the merged config
+	logSubsystemRender = "render"
+	// translating and parsing single inputs, including the transpile cache
+	logSubsystemTranspile = "transpile"
+)
+
+// Add the logging subsystems to the context of a render.
+func withLogSubsystems(ctx context.Context) context.Context {
+	for _, subsystem := range []string{logSubsystemRender, logSubsystemTranspile} {
+		ctx = tflog.NewSubsystem(ctx, subsystem,
+			tflog.WithLevelFromEnv("TF_LOG_PROVIDER_IGNITION", subsystem),
+			tflog.WithRootFields(),
+		)
+	}
+	return ctx
+}
+
+// Fields describing a Butane input. Inputs may contain secrets, so only their
+// header and size are logged.
+func butaneInputFields(butaneConfig string) map[string]interface{} {
+	var header struct {
+		Variant string `yaml:"variant"`
+		Version string `yaml:"version"`
+	}
+	// inputs which are not valid YAML fail translation and are logged without a header
+	_ = yaml.Unmarshal([]byte(butaneConfig), &header)
+	return map[string]interface{}{
+		"variant":        header.Variant,
+		"butane_version": header.Version,
+		"input_bytes":    len(butaneConfig),
+	}
+}
+
+// Number of report entries by severity. Entries are not logged, as their
+// messages may quote the input.
+func reportFields(r report.Report) map[string]interface{} {
+	var errors, warnings, infos int
+	for _, entry := range r.Entries {
+		switch entry.Kind {
+		case report.Error:
+			errors++
+		case report.Warn:
+			warnings++
+		case report.Info:
+			infos++
+		}
+	}
+	return map[string]interface{}{"report_errors": errors, "report_warnings": warnings, "report_infos": infos}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestRenderLogging(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	content := "variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/logging-test\n      contents:\n        inline: hunter2-secret\n"
+	snippet := "variant: fcos\nversion: 1.4.0\npasswd:\n  users:\n    - name: logging-test\n      password_hash: $y$j9T$secret-hash\n"
+	if _, err := Render(ctx, Options{Content: content, Snippets: []string{snippet}}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "hunter2") || strings.Contains(output.String(), "secret-hash") {
+		t.Errorf("inputs must not be logged:\n%s", output.String())
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := map[string]map[string]interface{}{}
+	for _, entry := range entries {
+		key := entry["@message"].(string)
+		if input, ok := entry["input"]; ok {
+			key += " " + input.(string)
+		}
+		messages[key] = entry
+	}
+	for key, fields := range map[string]map[string]interface{}{
+		"transpiled butane input content":   {"@module": "provider.transpile", "butane_version": "1.5.0", "ignition_version": "3.4.0", "variant": "fcos", "report_warnings": float64(0)},
+		"transpiled butane input snippet 0": {"@module": "provider.transpile", "butane_version": "1.4.0", "ignition_version": "3.4.0", "input_bytes": float64(len(snippet))},
+		"transpile cache miss content":      {"cache_misses": nil},
+		"merged snippets into content":      {"@module": "provider.render", "snippets": float64(1), "merge_ms": nil},
+		"rendered config":                   {"@module": "provider.render", "output_bytes": nil},
+	} {
+		entry, ok := messages[key]
+		if !ok {
+			t.Errorf("missing log entry %q", key)
+			continue
+		}
+		for field, expected := range fields {
+			if value, ok := entry[field]; !ok || (expected != nil && value != expected) {
+				t.Errorf("%s: expected %s to be %v, got %v", key, field, expected, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+// Locations of a variant's root filesystem Ignition can write to.
+type variantPaths struct {
+	// writes below these directories fail at boot
+	readOnly []string
+	// exceptions from readOnly, e.g. /usr/local which is a symlink into /var on FCOS
+	writable []string
+	// tmpfs mounts, everything written there is lost at reboot
+	volatile []string
+	// directory for binaries, and the replacement of /usr for other files
+	binDirectory   string
+	usrReplacement string
+}
+
+var variantPathRules = map[string]variantPaths{
+	"fcos": {
+		readOnly:       []string{"/usr", "/bin", "/sbin", "/lib", "/lib64", "/boot", "/sysroot", "/ostree", "/proc", "/sys", "/dev"},
+		writable:       []string{"/usr/local"},
+		volatile:       []string{"/run", "/var/run", "/tmp"},
+		binDirectory:   "/usr/local/bin",
+		usrReplacement: "/usr/local",
+	},
+	"flatcar": {
+		readOnly:       []string{"/usr", "/bin", "/sbin", "/lib", "/lib64", "/boot", "/proc", "/sys", "/dev"},
+		volatile:       []string{"/run", "/var/run", "/tmp"},
+		binDirectory:   "/opt/bin",
+		usrReplacement: "/opt",
+	},
+}
+
+// Configuration directories below /usr/lib, which have a counterpart in /etc.
+var vendorConfigDirectories = []string{
+	"systemd", "sysctl.d", "modules-load.d", "modprobe.d", "tmpfiles.d", "sysusers.d",
+	"udev/rules.d", "environment.d", "NetworkManager",
+}
+
+var unitExtensions = []string{
+	".service", ".socket", ".timer", ".mount", ".automount", ".path", ".target", ".slice", ".swap",
+}
+
+// Directories systemd loads system and user units from.
+var unitDirectories = []string{"/etc/systemd/system", "/etc/systemd/user"}
+
+func hasPathPrefix(p, prefix string) bool {
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// Check the files, directories and links of a config against the writable areas
+// and conventions of a variant. Writes to read-only locations are errors, writes to
+// volatile locations and unit files systemd ignores are warnings.
+// Unknown variants are not checked.
+func checkPaths(config types_v3_4.Config, variant string, inputs *conflictTracker) Diagnostics {
+	var diags Diagnostics
+	rules, ok := variantPathRules[variant]
+	if !ok {
+		return diags
+	}
+	report := func(severity Severity, kind, nodePath, format string, args ...interface{}) {
+		name := fmt.Sprintf("%s %s", kind, nodePath)
+		if input := inputs.input("path " + nodePath); input != "" {
+			name = fmt.Sprintf("%s (%s)", name, input)
+		}
+		diags = append(diags, Diagnostic{
+			Severity: severity,
+			Summary:  "path problem",
+			Detail:   fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)),
+		})
+	}
+	check := func(kind, nodePath string) {
+		p := path.Clean(nodePath)
+		if prefix := rules.readOnlyPrefix(p); prefix != "" {
+			if suggestion := rules.suggest(p); suggestion != "" {
+				report(SeverityError, kind, nodePath, "%s is read-only on %s, use %s instead", prefix, variant, suggestion)
+			} else {
+				report(SeverityError, kind, nodePath, "%s is read-only on %s", prefix, variant)
+			}
+			return
+		}
+		for _, prefix := range rules.volatile {
+			if hasPathPrefix(p, prefix) {
+				report(SeverityWarning, kind, nodePath, "%s is a tmpfs on %s and lost at reboot, write to /etc or /var, or create it at boot with systemd-tmpfiles", prefix, variant)
+				return
+			}
+		}
+		if kind != "directory" && ignoredUnitFile(p) {
+			report(SeverityWarning, kind, nodePath, "systemd does not load units from %s, use /etc/systemd/system/%s or systemd.units instead", path.Dir(p), path.Base(p))
+		}
+	}
+	for _, file := range config.Storage.Files {
+		check("file", file.Path)
+	}
+	for _, directory := range config.Storage.Directories {
+		check("directory", directory.Path)
+	}
+	for _, link := range config.Storage.Links {
+		check("link", link.Path)
+	}
+	return diags
+}
+
+// Returns the read-only directory containing a path, or an empty string if it is writable.
+func (rules variantPaths) readOnlyPrefix(p string) string {
+	for _, prefix := range rules.writable {
+		if hasPathPrefix(p, prefix) {
+			return ""
+		}
+	}
+	for _, prefix := range rules.readOnly {
+		if hasPathPrefix(p, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// Suggest a writable location for a path in a read-only directory.
+func (rules variantPaths) suggest(p string) string {
+	for _, prefix := range []string{"/usr/lib", "/lib", "/lib64"} {
+		if !hasPathPrefix(p, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(p, prefix+"/")
+		for _, directory := range vendorConfigDirectories {
+			if hasPathPrefix(rest, directory) {
+				return path.Join("/etc", rest)
+			}
+		}
+	}
+	for _, prefix := range []string{"/usr/bin", "/usr/sbin", "/bin", "/sbin", "/usr/local/bin", "/usr/local/sbin"} {
+		if hasPathPrefix(p, prefix) && p != prefix {
+			return path.Join(rules.binDirectory, strings.TrimPrefix(p, prefix+"/"))
+		}
+	}
+	for _, prefix := range []string{"/usr/local", "/usr"} {
+		if hasPathPrefix(p, prefix) && p != prefix {
+			return path.Join(rules.usrReplacement, strings.TrimPrefix(p, prefix+"/"))
+		}
+	}
+	return ""
+}
+
+// Returns true for unit files below /etc/systemd which are not in a unit directory,
+// its dropin directories or its .wants and .requires directories.
+func ignoredUnitFile(p string) bool {
+	if !hasPathPrefix(p, "/etc/systemd") {
+		return false
+	}
+	isUnit := false
+	for _, extension := range unitExtensions {
+		isUnit = isUnit || strings.HasSuffix(p, extension)
+	}
+	if !isUnit {
+		return false
+	}
+	dir := path.Dir(p)
+	for _, unitDirectory := range unitDirectories {
+		if dir == unitDirectory {
+			return false
+		}
+		if path.Dir(dir) == unitDirectory && (strings.HasSuffix(dir, ".wants") || strings.HasSuffix(dir, ".requires")) {
+			return false
+		}
+	}
+	return true
+}
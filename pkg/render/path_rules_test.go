@@ -0,0 +1,63 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestCheckPaths(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"storage": {
+			"files": [
+				{"path": "/usr/local/bin/tool"},
+				{"path": "/usr/lib/sysctl.d/90-net.conf"},
+				{"path": "/usr/share/app/data"},
+				{"path": "/run/app.pid"},
+				{"path": "/etc/systemd/app.service"},
+				{"path": "/etc/systemd/system/app.service"},
+				{"path": "/etc/systemd/system/multi-user.target.wants/app.service"}
+			],
+			"directories": [{"path": "/boot/extra"}],
+			"links": [{"path": "/bin/tool", "target": "/opt/bin/tool"}]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := newConflictTracker()
+
+	expected := map[string][]string{
+		"fcos": {
+			"file /usr/lib/sysctl.d/90-net.conf: /usr is read-only on fcos, use /etc/sysctl.d/90-net.conf instead",
+			"file /usr/share/app/data: /usr is read-only on fcos, use /usr/local/share/app/data instead",
+			"file /run/app.pid: /run is a tmpfs on fcos and lost at reboot, write to /etc or /var, or create it at boot with systemd-tmpfiles",
+			"file /etc/systemd/app.service: systemd does not load units from /etc/systemd, use /etc/systemd/system/app.service or systemd.units instead",
+			"directory /boot/extra: /boot is read-only on fcos",
+			"link /bin/tool: /bin is read-only on fcos, use /usr/local/bin/tool instead",
+		},
+		"flatcar": {
+			"file /usr/local/bin/tool: /usr is read-only on flatcar, use /opt/bin/tool instead",
+			"file /usr/lib/sysctl.d/90-net.conf: /usr is read-only on flatcar, use /etc/sysctl.d/90-net.conf instead",
+			"file /usr/share/app/data: /usr is read-only on flatcar, use /opt/share/app/data instead",
+			"file /run/app.pid: /run is a tmpfs on flatcar and lost at reboot, write to /etc or /var, or create it at boot with systemd-tmpfiles",
+			"file /etc/systemd/app.service: systemd does not load units from /etc/systemd, use /etc/systemd/system/app.service or systemd.units instead",
+			"directory /boot/extra: /boot is read-only on flatcar",
+			"link /bin/tool: /bin is read-only on flatcar, use /opt/bin/tool instead",
+		},
+		"openshift": {},
+	}
+	for variant, messages := range expected {
+		diags := checkPaths(config, variant, inputs)
+		if len(diags) != len(messages) {
+			t.Errorf("%s: expected %d problems, got %d: %v", variant, len(messages), len(diags), diags)
+			continue
+		}
+		for i := range messages {
+			if diags[i].Detail != messages[i] {
+				t.Errorf("%s: expected %q, got %q", variant, messages[i], diags[i].Detail)
+			}
+		}
+	}
+}
@@ -0,0 +1,417 @@
+// Package render transpiles Butane configs to Ignition exactly like the ignition_config
+// data source of the Terraform provider: content and snippets are translated, merged
+// in input order and the merged config is checked, e.g. for unit problems, unknown
+// accounts, overlapping partitions, secrets and policy rules.
+//
+//	options := render.DefaultOptions()
+//	options.Content = worker
+//	options.Snippets = []string{units, storage}
+//	result, err := render.Render(ctx, options)
+//
+// Render is safe for concurrent use. Translated inputs are cached in memory,
+// so inputs shared by several configs are translated once.
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/coreos/vcontext/report"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// Determines the Ignition spec version of the rendered config.
+type VersionStrategy string
+
+const (
+	// the version of the content; snippets must not be newer
+	VersionContent VersionStrategy = "content"
+	// the newest version of content and snippets; older inputs are translated to it
+	VersionNewest VersionStrategy = "newest"
+	// the newest supported version; all inputs are translated to it
+	VersionLatest VersionStrategy = "latest"
+)
+
+// Inputs and checks of a render. The zero value renders without any checks,
+// DefaultOptions returns the defaults of the ignition_config data source.
+type Options struct {
+	// Butane config determining the variant of the rendered config
+	Content string
+	// Butane configs merged into the content in order
+	Snippets []string
+	// fail on Butane translation warnings
+	Strict bool
+	// indent the rendered config
+	PrettyPrint bool
+	// directory of local files embedded by inputs, e.g. with contents.local;
+	// local files cannot be embedded if empty
+	FilesDir string
+	// VersionContent if empty
+	VersionStrategy VersionStrategy
+	// ConflictLastWins if empty
+	ConflictPolicy ConflictPolicy
+	// report problems of units and dropins and of the unit dependency graph as warnings
+	LintUnits bool
+	// check nodes against the writable locations of the variant
+	ValidatePaths bool
+	// check users and groups referenced by nodes, units and passwd
+	ValidateAccounts bool
+	// validate the syntax of inline files
+	ValidateFiles bool
+	// formats of files by path or path pattern, e.g. {"/etc/app/*.conf": "toml"}
+	FileFormats map[string]string
+	// check the partition layout and device references
+	ValidateStorage bool
+	// severity of the problems found by the checks of paths, accounts and storage,
+	// which rely on built-in knowledge of the variants; SeverityWarning if empty
+	CheckSeverity Severity
+	// sizes of disks in MiB by device
+	DiskSizes map[string]int
+	// SecretScanOff if empty
+	ScanSecrets SecretScan
+	// file paths, unit names or patterns of them which may contain secrets
+	SecretAllowlist []string
+	// policy rules the merged config must comply with
+	Rules []Rule
+}
+
+// Returns the options of an ignition_config data source with all arguments at their defaults.
+func DefaultOptions() Options {
+	return Options{
+		VersionStrategy:  VersionContent,
+		ConflictPolicy:   ConflictLastWins,
+		LintUnits:        true,
+		ValidatePaths:    true,
+		ValidateAccounts: true,
+		ValidateStorage:  true,
+		CheckSeverity:    SeverityWarning,
+		ScanSecrets:      SecretScanOff,
+	}
+}
+
+// A rendered Ignition config.
+type Result struct {
+	// Ignition config, indented if PrettyPrint is set
+	Rendered []byte
+	// Ignition spec version of the rendered config
+	IgnitionVersion string
+	// problems which did not fail the render
+	Warnings Diagnostics
+	// entries of the Butane translation reports of the inputs
+	Report []ReportEntry
+	// dependency graph of the systemd units in the DOT language
+	UnitGraph string
+	// partitions of the disks as they are placed by Ignition
+	StorageLayout []Partition
+}
+
+// An entry of the Butane translation report of an input.
+type ReportEntry struct {
+	// "content" or "snippet <index>"
+	Input string
+	// "error", "warning" or "info"
+	Kind string
+	// message with the position of the entry in the input
+	Message string
+}
+
+// Transpile and merge content and snippets, then check the merged config. Problems
+// which fail the render are returned as *Error, along with all warnings found before.
+func Render(ctx context.Context, options Options) (Result, error) {
+	ctx = withLogSubsystems(ctx)
+	result, diags := render(ctx, options)
+	if diags.HasError() {
+		return Result{}, &Error{Diagnostics: diags}
+	}
+	result.Warnings = diags
+	return result, nil
+}
+
+func render(ctx context.Context, options Options) (Result, Diagnostics) {
+	if err := validateOptions(options); err != nil {
+		return Result{}, errorDiagnostics(err)
+	}
+	rules, err := compileRules(options.Rules)
+	if err != nil {
+		return Result{}, errorDiagnostics(err)
+	}
+	tflog.SubsystemDebug(ctx, logSubsystemRender, "rendering content and snippets", map[string]interface{}{
+		"content_bytes":     len(options.Content),
+		"snippets":          len(options.Snippets),
+		"strict":            options.Strict,
+		"files_dir":         options.FilesDir,
+		"version_strategy":  string(options.VersionStrategy),
+		"conflict_policy":   string(options.ConflictPolicy),
+		"lint_units":        options.LintUnits,
+		"validate_paths":    options.ValidatePaths,
+		"validate_accounts": options.ValidateAccounts,
+		"validate_files":    options.ValidateFiles,
+		"validate_storage":  options.ValidateStorage,
+		"check_severity":    string(options.CheckSeverity),
+		"scan_secrets":      string(options.ScanSecrets),
+		"rules":             len(rules),
+	})
+	translate := translateOptions{strict: options.Strict, filesDir: options.FilesDir}
+
+	// transpile content
+	var maxVersion *semver.Version
+	if options.VersionStrategy == VersionLatest {
+		maxVersion = &types_v3_4.MaxVersion
+	}
+	contentCtx := tflog.SubsystemSetField(ctx, logSubsystemTranspile, "input", "content")
+	content := transpileButane(contentCtx, options.Content, translate, maxVersion)
+	if content.err != nil {
+		return Result{}, errorDiagnostics(fmt.Errorf("content parse error: %v", content.err))
+	}
+	target := content.version
+
+	// transpile snippets
+	start := time.Now()
+	var snippets []transpiledSnippet
+	var diags Diagnostics
+	if options.VersionStrategy == VersionNewest {
+		// transpile the snippets as their own version first to find the newest one
+		if snippets, diags = transpileSnippets(ctx, options.Snippets, translate, nil); diags.HasError() {
+			return Result{}, diags
+		}
+		for _, snippet := range snippets {
+			if target.LessThan(snippet.version) {
+				target = snippet.version
+			}
+		}
+		if content.version != target {
+			content = transpileButane(contentCtx, options.Content, translate, &target)
+			if content.err != nil {
+				return Result{}, errorDiagnostics(fmt.Errorf("content parse error: %v", content.err))
+			}
+		}
+	}
+	if snippets == nil || !sameVersion(snippets, target) {
+		if snippets, diags = transpileSnippets(ctx, options.Snippets, translate, &target); diags.HasError() {
+			return Result{}, diags
+		}
+	}
+	tflog.SubsystemDebug(ctx, logSubsystemRender, "transpiled snippets", map[string]interface{}{
+		"snippets":    len(snippets),
+		"duration_ms": milliseconds(time.Since(start)),
+	})
+
+	// merge snippets with content in input order
+	start = time.Now()
+	inputs := newConflictTracker()
+	config, err := configutil.Normalize(content.config)
+	if err != nil {
+		return Result{}, errorDiagnostics(err)
+	}
+	inputs.add("content", config)
+	ignitionConfig := content.config
+	result := Result{IgnitionVersion: target.String(), Report: reportEntries("content", content.report)}
+	for i, snippet := range snippets {
+		input := fmt.Sprintf("snippet %d", i)
+		inputs.add(input, snippet.normalized)
+		ignitionConfig = content.ignition.Merge(ignitionConfig, snippet.config)
+		result.Report = append(result.Report, reportEntries(input, snippet.report)...)
+	}
+	tflog.SubsystemDebug(ctx, logSubsystemRender, "merged snippets into content", map[string]interface{}{
+		"snippets":         len(snippets),
+		"ignition_version": result.IgnitionVersion,
+		"merge_ms":         milliseconds(time.Since(start)),
+	})
+
+	// check the merged config
+	start = time.Now()
+	diags = inputs.diagnostics(options.ConflictPolicy)
+	if diags.HasError() {
+		return Result{}, diags
+	}
+	merged, err := configutil.Normalize(ignitionConfig)
+	if err != nil {
+		return Result{}, errorDiagnostics(err)
+	}
+	graph, layout, checkDiags := checkConfig(merged, butaneVariant(options.Content), options, rules, inputs)
+	diags = append(diags, checkDiags...)
+	tflog.SubsystemDebug(ctx, logSubsystemRender, "checked merged config", map[string]interface{}{
+		"errors":    countSeverity(diags, SeverityError),
+		"warnings":  countSeverity(diags, SeverityWarning),
+		"checks_ms": milliseconds(time.Since(start)),
+	})
+	if diags.HasError() {
+		return Result{}, diags
+	}
+	result.UnitGraph = graph.dot()
+	result.StorageLayout = layout
+
+	// marshal json
+	if options.PrettyPrint {
+		result.Rendered, err = json.MarshalIndent(ignitionConfig, "", "  ")
+	} else {
+		result.Rendered, err = json.Marshal(ignitionConfig)
+	}
+	if err != nil {
+		return Result{}, append(diags, errorDiagnostics(err)...)
+	}
+	tflog.SubsystemDebug(ctx, logSubsystemRender, "rendered config", map[string]interface{}{
+		"ignition_version": result.IgnitionVersion,
+		"output_bytes":     len(result.Rendered),
+	})
+	return result, diags
+}
+
+// Check the merged config as enabled by the options. Conflicts, unit problems and rule
+// violations with severity warning are returned as warnings, as are the problems of the
+// path, account and storage checks unless CheckSeverity is SeverityError; checks stop at
+// the first one which finds errors.
+func checkConfig(
+	merged types_v3_4.Config,
+	variant string,
+	options Options,
+	rules []policyRule,
+	inputs *conflictTracker,
+) (*unitGraph, []Partition, Diagnostics) {
+	var diags Diagnostics
+	checkDiagnostics := func(checkDiags Diagnostics) Diagnostics {
+		if options.CheckSeverity != SeverityError {
+			for i := range checkDiags {
+				checkDiags[i].Severity = SeverityWarning
+			}
+		}
+		return checkDiags
+	}
+
+	// check paths
+	if options.ValidatePaths {
+		if diags = append(diags, checkDiagnostics(checkPaths(merged, variant, inputs))...); diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+
+	// check account references and password hashes
+	if options.ValidateAccounts {
+		accountDiags := append(checkPasswordHashes(merged, inputs), checkAccounts(merged, variant, inputs)...)
+		if diags = append(diags, checkDiagnostics(accountDiags)...); diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+
+	// analyze storage
+	layout, storageDiags := analyzeStorage(merged, variant, options.DiskSizes, inputs)
+	if options.ValidateStorage {
+		if diags = append(diags, checkDiagnostics(storageDiags)...); diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+
+	// lint units
+	graph := newUnitGraph(merged, variant)
+	if options.LintUnits {
+		problems := append(lintUnits(merged, inputs), graph.problems(inputs)...)
+		for _, problem := range problems {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Summary:  "systemd unit problem",
+				Detail:   problem,
+			})
+		}
+	}
+
+	// validate files
+	if options.ValidateFiles {
+		problems, err := validateFiles(merged, options.FileFormats, inputs)
+		if err != nil {
+			return nil, nil, append(diags, errorDiagnostics(err)...)
+		}
+		for _, problem := range problems {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityError,
+				Summary:  "invalid file contents",
+				Detail:   problem,
+			})
+		}
+		if diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+
+	// scan for secrets
+	if options.ScanSecrets == SecretScanWarn || options.ScanSecrets == SecretScanError {
+		severity := SeverityWarning
+		if options.ScanSecrets == SecretScanError {
+			severity = SeverityError
+		}
+		secretDiags, err := scanSecrets(merged, options.SecretAllowlist, severity, inputs)
+		if err != nil {
+			return nil, nil, append(diags, errorDiagnostics(err)...)
+		}
+		if diags = append(diags, secretDiags...); diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+
+	// check rules
+	diags = append(diags, evaluateRules(merged, rules)...)
+	return graph, layout, diags
+}
+
+// Check the options which are not checked by the check using them.
+func validateOptions(options Options) error {
+	switch options.VersionStrategy {
+	case "", VersionContent, VersionNewest, VersionLatest:
+	default:
+		return fmt.Errorf("version strategy must be %s, %s or %s, got %q", VersionContent, VersionNewest, VersionLatest, options.VersionStrategy)
+	}
+	switch options.ConflictPolicy {
+	case "", ConflictLastWins, ConflictWarn, ConflictError:
+	default:
+		return fmt.Errorf("conflict policy must be %s, %s or %s, got %q", ConflictLastWins, ConflictWarn, ConflictError, options.ConflictPolicy)
+	}
+	switch options.CheckSeverity {
+	case "", SeverityWarning, SeverityError:
+	default:
+		return fmt.Errorf("check severity must be %s or %s, got %q", SeverityWarning, SeverityError, options.CheckSeverity)
+	}
+	switch options.ScanSecrets {
+	case "", SecretScanOff, SecretScanWarn, SecretScanError:
+	default:
+		return fmt.Errorf("secret scan must be %s, %s or %s, got %q", SecretScanOff, SecretScanWarn, SecretScanError, options.ScanSecrets)
+	}
+	for device, size := range options.DiskSizes {
+		if size <= 0 {
+			return fmt.Errorf("disk_sizes: size of %s must be positive, got %d", device, size)
+		}
+	}
+	return nil
+}
+
+// Returns true if all snippets were transpiled as version.
+func sameVersion(snippets []transpiledSnippet, version semver.Version) bool {
+	for _, snippet := range snippets {
+		if snippet.version != version {
+			return false
+		}
+	}
+	return true
+}
+
+func reportEntries(input string, r report.Report) []ReportEntry {
+	var entries []ReportEntry
+	for _, entry := range r.Entries {
+		entries = append(entries, ReportEntry{Input: input, Kind: entry.Kind.String(), Message: entry.String()})
+	}
+	return entries
+}
+
+func countSeverity(diags Diagnostics, severity Severity) int {
+	count := 0
+	for _, d := range diags {
+		if d.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,296 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/google/cel-go/cel"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// A policy rule the merged config must comply with.
+type Rule struct {
+	// name of a built-in rule, or of a custom rule if Expression is set
+	Name string
+	// CEL expression which evaluates to true if the config complies. The config is
+	// available as config, using the field names of the Ignition specification.
+	Expression string
+	// message shown when the rule is violated
+	Message string
+	// severity of violations, SeverityError if empty
+	Severity Severity
+	// hosts remote sources may be fetched from, used by allowed_source_hosts
+	AllowedHosts []string
+}
+
+// A rule with its implementation. Built-in rules are implemented in Go, custom rules are CEL programs.
+type policyRule struct {
+	Rule
+	builtin builtinRule
+	program cel.Program
+}
+
+// Returns a description of every violation of the rule.
+type builtinRule func(config types_v3_4.Config, rule policyRule) []string
+
+var builtinRules = map[string]builtinRule{
+	"no_core_password":       ruleNoCorePassword,
+	"no_world_writable":      ruleNoWorldWritable,
+	"https_sources":          ruleHTTPSSources,
+	"allowed_source_hosts":   ruleAllowedSourceHosts,
+	"no_root_ssh_keys":       ruleNoRootSSHKeys,
+	"no_setuid_inline_files": ruleNoSetuidInlineFiles,
+}
+
+// Compiled CEL programs by expression, as the same rules are evaluated for every config.
+// Programs are safe for concurrent use.
+var rulePrograms sync.Map
+
+// Check that all rules are built-in rules or have a valid expression, so that
+// errors can be reported before any config is rendered.
+func ValidateRules(rules []Rule) error {
+	_, err := compileRules(rules)
+	return err
+}
+
+func compileRules(rules []Rule) ([]policyRule, error) {
+	compiled := make([]policyRule, 0, len(rules))
+	for _, rule := range rules {
+		policy := policyRule{Rule: rule}
+		if rule.Severity != SeverityError && rule.Severity != SeverityWarning && rule.Severity != "" {
+			return nil, fmt.Errorf("rule %s: severity must be %s or %s, got %q", rule.Name, SeverityError, SeverityWarning, rule.Severity)
+		}
+		if rule.Expression != "" {
+			program, err := compileRuleExpression(rule.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+			}
+			policy.program = program
+		} else if builtin, ok := builtinRules[rule.Name]; ok {
+			policy.builtin = builtin
+		} else {
+			return nil, fmt.Errorf(
+				"rule %s: unknown built-in rule, set an expression for custom rules or use one of %s",
+				rule.Name, strings.Join(builtinRuleNames(), ", "),
+			)
+		}
+		compiled = append(compiled, policy)
+	}
+	return compiled, nil
+}
+
+var ruleEnvironment = mustRuleEnvironment()
+
+// Returns the CEL environment of custom rules, with the merged config as variable config.
+// The environment is static, so failing to create it is a bug and panics at init.
+func mustRuleEnvironment() *cel.Env {
+	env, err := cel.NewEnv(cel.Variable("config", cel.MapType(cel.StringType, cel.DynType)))
+	if err != nil {
+		panic(fmt.Sprintf("rules: cannot create the CEL environment: %v", err))
+	}
+	return env
+}
+
+func compileRuleExpression(expression string) (cel.Program, error) {
+	if program, ok := rulePrograms.Load(expression); ok {
+		return program.(cel.Program), nil
+	}
+	ast, issues := ruleEnvironment.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to bool, not %s", ast.OutputType())
+	}
+	program, err := ruleEnvironment.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	rulePrograms.Store(expression, program)
+	return program, nil
+}
+
+// Check the config against all rules. Violations are reported with the severity of their rule.
+func evaluateRules(config types_v3_4.Config, rules []policyRule) Diagnostics {
+	var diags Diagnostics
+	if len(rules) == 0 {
+		return diags
+	}
+	var activation map[string]interface{}
+	for _, rule := range rules {
+		var violations []string
+		if rule.builtin != nil {
+			violations = rule.builtin(config, rule)
+		} else {
+			if activation == nil {
+				var err error
+				if activation, err = ruleActivation(config); err != nil {
+					return append(diags, errorDiagnostics(err)...)
+				}
+			}
+			out, _, err := rule.program.Eval(activation)
+			if err != nil {
+				violations = []string{fmt.Sprintf("evaluation error: %v", err)}
+			} else if complies, ok := out.Value().(bool); !ok {
+				violations = []string{fmt.Sprintf("expression evaluated to %v instead of bool", out.Value())}
+			} else if !complies {
+				violations = []string{"expression evaluated to false"}
+			}
+		}
+		for _, violation := range violations {
+			diags = append(diags, ruleDiagnostic(rule, violation))
+		}
+	}
+	return diags
+}
+
+func ruleDiagnostic(rule policyRule, violation string) Diagnostic {
+	severity := SeverityError
+	if rule.Severity == SeverityWarning {
+		severity = SeverityWarning
+	}
+	summary := fmt.Sprintf("rule %s violated", rule.Name)
+	if rule.Message != "" {
+		summary = fmt.Sprintf("rule %s violated: %s", rule.Name, rule.Message)
+	}
+	return Diagnostic{
+		Severity: severity,
+		Summary:  summary,
+		Detail:   violation,
+	}
+}
+
+// Expose the config to CEL with its Ignition JSON field names.
+func ruleActivation(config types_v3_4.Config) (map[string]interface{}, error) {
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(rawConfig, &value); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"config": integralNumbers(value)}, nil
+}
+
+// JSON numbers decode as float64, but Ignition only knows integers.
+func integralNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = integralNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = integralNumbers(child)
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v)
+		}
+	}
+	return value
+}
+
+func ruleNoCorePassword(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, user := range config.Passwd.Users {
+		if user.Name == "core" && configutil.StringValue(user.PasswordHash) != "" {
+			violations = append(violations, "user core has a password hash, password logins are not allowed")
+		}
+	}
+	return violations
+}
+
+func ruleNoWorldWritable(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, file := range config.Storage.Files {
+		if file.Mode != nil && *file.Mode&0o002 != 0 {
+			violations = append(violations, fmt.Sprintf("file %s is world-writable (mode %s)", file.Path, configutil.FormatMode(file.Mode)))
+		}
+	}
+	for _, directory := range config.Storage.Directories {
+		if directory.Mode != nil && *directory.Mode&0o002 != 0 {
+			violations = append(violations, fmt.Sprintf("directory %s is world-writable (mode %s)", directory.Path, configutil.FormatMode(directory.Mode)))
+		}
+	}
+	return violations
+}
+
+func ruleHTTPSSources(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, resource := range configutil.Resources(config) {
+		source := configutil.RemoteSource(resource.Resource)
+		if source == "" {
+			continue
+		}
+		if parsed, err := url.Parse(source); err != nil || parsed.Scheme != "https" {
+			violations = append(violations, fmt.Sprintf("%s is not fetched over https: %s", resource.Name, source))
+		}
+		if resource.Resource.Verification.Hash == nil {
+			violations = append(violations, fmt.Sprintf("%s has no verification hash: %s", resource.Name, source))
+		}
+	}
+	return violations
+}
+
+func ruleAllowedSourceHosts(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, resource := range configutil.Resources(config) {
+		source := configutil.RemoteSource(resource.Resource)
+		if source == "" {
+			continue
+		}
+		parsed, err := url.Parse(source)
+		if err != nil || !containsHost(rule.AllowedHosts, parsed.Hostname()) {
+			violations = append(violations, fmt.Sprintf("%s is fetched from a host which is not allowed: %s", resource.Name, source))
+		}
+	}
+	return violations
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, allowed := range hosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleNoRootSSHKeys(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, user := range config.Passwd.Users {
+		if user.Name == "root" && len(user.SSHAuthorizedKeys) > 0 {
+			violations = append(violations, fmt.Sprintf("user root has %d ssh authorized keys", len(user.SSHAuthorizedKeys)))
+		}
+	}
+	return violations
+}
+
+func ruleNoSetuidInlineFiles(config types_v3_4.Config, rule policyRule) []string {
+	var violations []string
+	for _, file := range config.Storage.Files {
+		if file.Mode != nil && *file.Mode&0o6000 != 0 && configutil.IsInlineResource(file.Contents) {
+			violations = append(violations, fmt.Sprintf("inline file %s has the setuid or setgid bit set (mode %s)", file.Path, configutil.FormatMode(file.Mode)))
+		}
+	}
+	return violations
+}
+
+// Names of all built-in rules, for error messages.
+func builtinRuleNames() []string {
+	names := make([]string, 0, len(builtinRules))
+	for name := range builtinRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
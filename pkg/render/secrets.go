@@ -0,0 +1,177 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+// How secrets found in the merged config are reported.
+type SecretScan string
+
+const (
+	// do not scan for secrets
+	SecretScanOff SecretScan = "off"
+	// report secrets as warnings
+	SecretScanWarn SecretScan = "warn"
+	// report secrets as errors
+	SecretScanError SecretScan = "error"
+)
+
+// A kind of secret recognized by a regular expression.
+type secretDetector struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretDetectors = []secretDetector{
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"AWS secret access key", regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}\b`)},
+	{"GCP service account key", regexp.MustCompile(`"type"\s*:\s*"service_account"`)},
+	{"GCP API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+// PEM blocks such as certificates contain base64 which looks like a high entropy token.
+var (
+	pemBegin = regexp.MustCompile(`^-----BEGIN [A-Z0-9 ]+-----`)
+	pemEnd   = regexp.MustCompile(`^-----END [A-Z0-9 ]+-----`)
+)
+
+// SSH public keys are not secret.
+var publicKeyPattern = regexp.MustCompile(`\b(ssh-(rsa|dss|ed25519)|ecdsa-sha2-nistp\d+|sk-(ssh-ed25519|ecdsa-sha2-nistp256)@openssh\.com) [A-Za-z0-9+/=]+`)
+
+// Tokens which are candidates for high entropy secrets, e.g. base64 encoded keys.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// Minimum Shannon entropy in bits per character of a high entropy token. Random base64
+// strings have about 5 to 6 bits, English text and identifiers less than 4.
+const minTokenEntropy = 4.5
+
+// Scan the contents of inline files, units and dropins of a config for secrets such
+// as private keys, cloud credentials and tokens. Files matching a path pattern of the
+// allowlist and units matching it by name are skipped. Remote contents are not fetched.
+func scanSecrets(config types_v3_4.Config, allowlist []string, severity Severity, inputs *conflictTracker) (Diagnostics, error) {
+	for _, pattern := range allowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("secret_allowlist: invalid pattern %q: %v", pattern, err)
+		}
+	}
+	allowed := func(name string) bool {
+		for _, pattern := range allowlist {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	var diags Diagnostics
+	scan := func(name, key string, contents string) {
+		findings := findSecrets(contents)
+		if len(findings) == 0 {
+			return
+		}
+		if input := inputs.input(key); input != "" {
+			name = fmt.Sprintf("%s (%s)", name, input)
+		}
+		diags = append(diags, Diagnostic{
+			Severity: severity,
+			Summary:  "possible secret",
+			Detail:   fmt.Sprintf("%s: %s, add it to secret_allowlist if this is intended", name, strings.Join(findings, ", ")),
+		})
+	}
+	for _, file := range config.Storage.Files {
+		if allowed(file.Path) {
+			continue
+		}
+		contents, ok, err := fileContents(file)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %v", file.Path, err)
+		}
+		if ok {
+			scan("file "+file.Path, "path "+file.Path, string(contents))
+		}
+	}
+	for _, unit := range config.Systemd.Units {
+		if allowed(unit.Name) {
+			continue
+		}
+		if unit.Contents != nil {
+			scan("unit "+unit.Name, "unit "+unit.Name, *unit.Contents)
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents != nil {
+				name := fmt.Sprintf("unit %s dropin %s", unit.Name, dropin.Name)
+				scan(name, name, *dropin.Contents)
+			}
+		}
+	}
+	return diags, nil
+}
+
+// Find secrets in contents, returning a description with the line number of each.
+// The secrets themselves are not part of the findings, so they do not end up in logs.
+func findSecrets(contents string) []string {
+	var findings []string
+	inPEM := false
+	for i, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inPEM {
+			inPEM = !pemEnd.MatchString(trimmed)
+			continue
+		}
+		inPEM = pemBegin.MatchString(trimmed)
+		line = publicKeyPattern.ReplaceAllString(line, "")
+		found := false
+		for _, detector := range secretDetectors {
+			if detector.pattern.MatchString(line) {
+				findings = append(findings, fmt.Sprintf("line %d: %s", i+1, detector.name))
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		for _, token := range tokenPattern.FindAllString(line, -1) {
+			if highEntropy(token) {
+				findings = append(findings, fmt.Sprintf("line %d: high entropy string", i+1))
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// Returns true for tokens mixing upper case letters, lower case letters and digits
+// with a high Shannon entropy. Hexadecimal strings such as checksums are never matched.
+func highEntropy(token string) bool {
+	var upper, lower, digit bool
+	counts := map[rune]int{}
+	for _, c := range token {
+		counts[c]++
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(token))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minTokenEntropy
+}
@@ -0,0 +1,264 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// GPT partition tables have 128 entries by default.
+const maxPartitionNumber = 128
+
+// Partition labels of the boot disk of a variant's base image.
+var variantPartitionLabels = map[string]map[string]bool{
+	"fcos":    stringSet("BIOS-BOOT", "EFI-SYSTEM", "PowerPC-PReP-boot", "boot", "root"),
+	"flatcar": stringSet("EFI-SYSTEM", "BIOS-BOOT", "USR-A", "USR-B", "OEM", "OEM-CONFIG", "coreos-reserved", "ROOT"),
+}
+
+// Position of a partition in the GPT of a disk after provisioning. Start and size
+// are 0 if they are unknown, e.g. because they depend on the size of the disk.
+type Partition struct {
+	Device   string
+	Number   int
+	Label    string
+	StartMiB int
+	SizeMiB  int
+}
+
+// An allocated range of a disk, end is 0 if the range extends to the unknown end of the disk.
+type extent struct {
+	name       string
+	start, end int
+}
+
+func (e extent) overlaps(other extent) bool {
+	return (e.end == 0 || other.start < e.end) && (other.end == 0 || e.start < other.end)
+}
+
+// Compute the partition layouts of the disks of a config and check them for overlapping
+// and out of range partitions, and check the devices of raids, luks volumes and
+// filesystems for references to partitions, raids and luks volumes which are not defined.
+// Disk sizes are in MiB and used to place partitions which fill the rest of a disk.
+func analyzeStorage(config types_v3_4.Config, variant string, diskSizes map[string]int, inputs *conflictTracker) ([]Partition, Diagnostics) {
+	var diags Diagnostics
+	report := func(severity Severity, name, key, format string, args ...interface{}) {
+		if input := inputs.input(key); input != "" {
+			name = fmt.Sprintf("%s (%s)", name, input)
+		}
+		diags = append(diags, Diagnostic{
+			Severity: severity,
+			Summary:  "storage problem",
+			Detail:   fmt.Sprintf("%s: %s", name, fmt.Sprintf(format, args...)),
+		})
+	}
+	var layout []Partition
+	for _, disk := range config.Storage.Disks {
+		layout = append(layout, layoutDisk(disk, diskSizes[disk.Device], report)...)
+	}
+	checkDeviceReferences(config, variant, report)
+	return layout, diags
+}
+
+// Place the partitions of a disk the way sgdisk does: partitions without a start are
+// positioned at the start of the largest free block, partitions without a size fill
+// the free block they start in, and partitions without a number get the lowest free
+// number. Partitions are aligned to 1 MiB, the first and last MiB of a disk hold the
+// partition tables. The size of the disk is 0 if it is unknown.
+func layoutDisk(disk types_v3_4.Disk, diskSize int, report func(Severity, string, string, string, ...interface{})) []Partition {
+	diskEnd := 0
+	if diskSize > 0 {
+		diskEnd = diskSize - 1
+	}
+	numbers := map[int]bool{}
+	for _, partition := range disk.Partitions {
+		numbers[partition.Number] = true
+	}
+	nextNumber := func() int {
+		for number := 1; ; number++ {
+			if !numbers[number] {
+				numbers[number] = true
+				return number
+			}
+		}
+	}
+
+	var layout []Partition
+	var allocated []extent
+	for _, partition := range disk.Partitions {
+		// deleted partitions do not take up space
+		if partition.ShouldExist != nil && !*partition.ShouldExist {
+			continue
+		}
+		number := partition.Number
+		key := fmt.Sprintf("partition %d on %s", number, disk.Device)
+		if number == 0 {
+			number = nextNumber()
+		}
+		name := fmt.Sprintf("partition %d on %s", number, disk.Device)
+		if label := configutil.StringValue(partition.Label); label != "" {
+			name = fmt.Sprintf("partition %d (%s) on %s", number, label, disk.Device)
+		}
+		entry := Partition{
+			Device:  disk.Device,
+			Number:  number,
+			Label:   configutil.StringValue(partition.Label),
+			SizeMiB: configutil.IntValue(partition.SizeMiB),
+		}
+		if number > maxPartitionNumber {
+			report(SeverityError, name, key, "number is beyond the %d entries of a partition table", maxPartitionNumber)
+		}
+
+		start, size := configutil.IntValue(partition.StartMiB), configutil.IntValue(partition.SizeMiB)
+		switch {
+		case start == 0 && partition.Number != 0 && partition.StartMiB == nil && !configutil.BoolValue(disk.WipeTable):
+			// the partition may already exist, in which case it keeps its start
+			layout = append(layout, entry)
+			continue
+		case start == 0:
+			block, ok := largestFreeBlock(allocated, diskEnd)
+			if !ok {
+				if diskEnd != 0 {
+					report(SeverityError, name, key, "no space left on the disk")
+				}
+				layout = append(layout, entry)
+				continue
+			}
+			start = block.start
+		}
+		end := 0
+		if size != 0 {
+			end = start + size
+		} else if block, ok := freeBlockAt(allocated, diskEnd, start); ok {
+			end = block.end
+		}
+		current := extent{name: name, start: start, end: end}
+		for _, other := range allocated {
+			if current.overlaps(other) {
+				report(SeverityError, name, key, "overlaps with %s", other.name)
+			}
+		}
+		if diskEnd != 0 && (start >= diskEnd || end > diskEnd) {
+			report(SeverityError, name, key, "ends beyond the usable end of the disk at %d MiB", diskEnd)
+		}
+		allocated = append(allocated, current)
+		entry.StartMiB = start
+		if end != 0 {
+			entry.SizeMiB = end - start
+		}
+		layout = append(layout, entry)
+	}
+	return layout
+}
+
+// Free blocks between allocated extents, ordered by start. The last block is open
+// ended if the end of the disk is unknown.
+func freeBlocks(allocated []extent, diskEnd int) []extent {
+	sorted := append([]extent(nil), allocated...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+	var blocks []extent
+	start := 1
+	for _, e := range sorted {
+		if e.start > start {
+			blocks = append(blocks, extent{start: start, end: e.start})
+		}
+		if e.end == 0 {
+			return blocks
+		}
+		if e.end > start {
+			start = e.end
+		}
+	}
+	if diskEnd == 0 || start < diskEnd {
+		blocks = append(blocks, extent{start: start, end: diskEnd})
+	}
+	return blocks
+}
+
+func largestFreeBlock(allocated []extent, diskEnd int) (extent, bool) {
+	var largest extent
+	found := false
+	for _, block := range freeBlocks(allocated, diskEnd) {
+		if block.end == 0 {
+			return block, true
+		}
+		if !found || block.end-block.start > largest.end-largest.start {
+			largest, found = block, true
+		}
+	}
+	return largest, found
+}
+
+func freeBlockAt(allocated []extent, diskEnd, start int) (extent, bool) {
+	for _, block := range freeBlocks(allocated, diskEnd) {
+		if start >= block.start && (block.end == 0 || start < block.end) {
+			return block, true
+		}
+	}
+	return extent{}, false
+}
+
+// Check references to partitions by label, to raids and to luks volumes. Partition
+// labels are only checked for known variants, as the labels of the base image are unknown otherwise.
+func checkDeviceReferences(config types_v3_4.Config, variant string, report func(Severity, string, string, string, ...interface{})) {
+	labels, checkLabels := map[string]bool{}, false
+	if builtin, ok := variantPartitionLabels[variant]; ok {
+		checkLabels = true
+		for label := range builtin {
+			labels[label] = true
+		}
+	}
+	for _, disk := range config.Storage.Disks {
+		for _, partition := range disk.Partitions {
+			if label := configutil.StringValue(partition.Label); label != "" {
+				labels[label] = true
+			}
+		}
+	}
+	raids := map[string]bool{}
+	for _, raid := range config.Storage.Raid {
+		raids[raid.Name] = true
+	}
+	volumes := map[string]bool{}
+	for _, luks := range config.Storage.Luks {
+		volumes[luks.Name] = true
+	}
+
+	check := func(name, device string) {
+		switch {
+		case strings.HasPrefix(device, "/dev/disk/by-partlabel/"):
+			label := strings.TrimPrefix(device, "/dev/disk/by-partlabel/")
+			if checkLabels && !labels[label] {
+				report(SeverityWarning, name, "", "no partition labeled %s is defined in storage.disks nor built into %s", label, variant)
+			}
+		case strings.HasPrefix(device, "/dev/md/"):
+			raid := strings.TrimPrefix(device, "/dev/md/")
+			if !raids[raid] {
+				report(SeverityWarning, name, "", "raid %s is not defined in storage.raid", raid)
+			}
+		case strings.HasPrefix(device, "/dev/mapper/") || strings.HasPrefix(device, "/dev/disk/by-id/dm-name-"):
+			volume := strings.TrimPrefix(strings.TrimPrefix(device, "/dev/mapper/"), "/dev/disk/by-id/dm-name-")
+			if !volumes[volume] {
+				report(SeverityWarning, name, "", "luks volume %s is not defined in storage.luks", volume)
+			}
+		}
+	}
+	for _, raid := range config.Storage.Raid {
+		for _, device := range raid.Devices {
+			check("raid "+raid.Name, string(device))
+		}
+	}
+	for _, luks := range config.Storage.Luks {
+		check("luks "+luks.Name, configutil.StringValue(luks.Device))
+	}
+	for _, filesystem := range config.Storage.Filesystems {
+		name := "filesystem " + filesystem.Device
+		if p := configutil.StringValue(filesystem.Path); p != "" {
+			name = "filesystem " + p
+		}
+		check(name, filesystem.Device)
+	}
+}
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestAnalyzeStorage(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"storage": {
+			"disks": [
+				{
+					"device": "/dev/vdb",
+					"wipeTable": true,
+					"partitions": [
+						{"label": "a", "sizeMiB": 1024},
+						{"label": "b", "startMiB": 2048, "sizeMiB": 512},
+						{"label": "c"},
+						{"label": "d", "startMiB": 500, "sizeMiB": 100}
+					]
+				},
+				{
+					"device": "/dev/disk/by-id/coreos-boot-disk",
+					"partitions": [
+						{"number": 4, "label": "root", "sizeMiB": 8192, "resize": true},
+						{"number": 200, "label": "var"}
+					]
+				},
+				{
+					"device": "/dev/vdc",
+					"wipeTable": true,
+					"partitions": [
+						{"number": 2, "shouldExist": false},
+						{"number": 1, "label": "big", "startMiB": 1, "sizeMiB": 2048}
+					]
+				}
+			],
+			"raid": [{"name": "data", "level": "raid1", "devices": ["/dev/disk/by-partlabel/a", "/dev/disk/by-partlabel/missing"]}],
+			"luks": [{"name": "secret", "device": "/dev/md/data"}, {"name": "other", "device": "/dev/md/nothing"}],
+			"filesystems": [
+				{"device": "/dev/mapper/secret", "path": "/var/secret", "format": "xfs"},
+				{"device": "/dev/disk/by-id/dm-name-gone", "format": "ext4"},
+				{"device": "/dev/disk/by-partlabel/root", "format": "xfs", "label": "root"}
+			]
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := newConflictTracker()
+
+	layout, diags := analyzeStorage(config, "fcos", map[string]int{"/dev/vdb": 4096, "/dev/vdc": 1024}, inputs)
+	expectedLayout := []Partition{
+		{Device: "/dev/vdb", Number: 1, Label: "a", StartMiB: 1, SizeMiB: 1024},
+		{Device: "/dev/vdb", Number: 2, Label: "b", StartMiB: 2048, SizeMiB: 512},
+		{Device: "/dev/vdb", Number: 3, Label: "c", StartMiB: 2560, SizeMiB: 1535},
+		{Device: "/dev/vdb", Number: 4, Label: "d", StartMiB: 500, SizeMiB: 100},
+		{Device: "/dev/disk/by-id/coreos-boot-disk", Number: 4, Label: "root", SizeMiB: 8192},
+		{Device: "/dev/disk/by-id/coreos-boot-disk", Number: 200, Label: "var"},
+		{Device: "/dev/vdc", Number: 1, Label: "big", StartMiB: 1, SizeMiB: 2048},
+	}
+	if !reflect.DeepEqual(layout, expectedLayout) {
+		t.Errorf("expected layout %v, got %v", expectedLayout, layout)
+	}
+	expected := []Diagnostic{
+		{Severity: SeverityError, Detail: "partition 4 (d) on /dev/vdb: overlaps with partition 1 (a) on /dev/vdb"},
+		{Severity: SeverityError, Detail: "partition 200 (var) on /dev/disk/by-id/coreos-boot-disk: number is beyond the 128 entries of a partition table"},
+		{Severity: SeverityError, Detail: "partition 1 (big) on /dev/vdc: ends beyond the usable end of the disk at 1023 MiB"},
+		{Severity: SeverityWarning, Detail: "raid data: no partition labeled missing is defined in storage.disks nor built into fcos"},
+		{Severity: SeverityWarning, Detail: "luks other: raid nothing is not defined in storage.raid"},
+		{Severity: SeverityWarning, Detail: "filesystem /dev/disk/by-id/dm-name-gone: luks volume gone is not defined in storage.luks"},
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(diags), diags)
+	}
+	for i := range expected {
+		if diags[i].Severity != expected[i].Severity || diags[i].Detail != expected[i].Detail {
+			t.Errorf("expected %v %q, got %v %q", expected[i].Severity, expected[i].Detail, diags[i].Severity, diags[i].Detail)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	butane "github.com/coreos/butane/config"
+	"github.com/coreos/butane/config/common"
+	"github.com/coreos/go-semver/semver"
+	ignition_util "github.com/coreos/ignition/v2/config/util"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+type getConfigVersion func(ignition []byte) (semver.Version, error)
+
+// Options of translating a single input.
+type translateOptions struct {
+	strict   bool
+	filesDir string
+}
+
+// Transpile Butane into a Ignition configuration object determined by the Ignitition version given.
+// Returns the Ignition configuration object, the Ignition version used and the matching Ignition library.
+// Without a maxVersion the version of the config is used, otherwise the config is parsed as maxVersion.
+// Results are cached, see transpileCache. Inputs embedding local files are only cached
+// within a context of WithLocalFilesCache, as the files may change between renders.
+func transpileButane(
+	ctx context.Context,
+	butaneConfig string,
+	options translateOptions,
+	maxVersion *semver.Version,
+) transpiled {
+	getIgnitionVersion := func(ignitionBytes []byte) (semver.Version, error) {
+		version, _, err := ignition_util.GetConfigVersion(ignitionBytes)
+		return version, err
+	}
+	var target string
+	if maxVersion != nil {
+		getIgnitionVersion = ensureMaxVersion(*maxVersion)
+		target = maxVersion.String()
+	}
+	translate := func() transpiled {
+		return translateButane(ctx, butaneConfig, options, getIgnitionVersion)
+	}
+	cache := providerTranspileCache
+	if options.filesDir != "" {
+		if cache = localFilesCache(ctx); cache == nil {
+			return translate()
+		}
+	}
+	return cache.get(ctx, newTranspileKey(butaneConfig, options, target), translate)
+}
+
+// Translate Butane and parse the result, logging sizes, timings and the number of report entries.
+func translateButane(
+	ctx context.Context,
+	butaneConfig string,
+	options translateOptions,
+	getIgnitionVersion getConfigVersion,
+) (result transpiled) {
+	fields := butaneInputFields(butaneConfig)
+	fields["strict"] = options.strict
+	defer func() {
+		fields["failed"] = result.err != nil
+		tflog.SubsystemDebug(ctx, logSubsystemTranspile, "transpiled butane input", fields)
+	}()
+
+	start := time.Now()
+	ignitionBytes, report, err := butane.TranslateBytes([]byte(butaneConfig), common.TranslateBytesOptions{
+		TranslateOptions: common.TranslateOptions{FilesDir: options.filesDir},
+	})
+	fields["translate_ms"] = milliseconds(time.Since(start))
+	for key, value := range reportFields(report) {
+		fields[key] = value
+	}
+	result.report = report
+	if err != nil {
+		result.err = err
+		return result
+	}
+	if options.strict && len(report.Entries) > 0 {
+		result.err = fmt.Errorf("strict parsing error: %v", report.String())
+		return result
+	}
+	if result.version, result.err = getIgnitionVersion(ignitionBytes); result.err != nil {
+		return result
+	}
+	fields["ignition_version"] = result.version.String()
+	fields["ignition_bytes"] = len(ignitionBytes)
+	if result.ignition, result.err = configutil.LibraryForVersion(result.version.String()); result.err != nil {
+		return result
+	}
+	start = time.Now()
+	result.config, _, result.err = result.ignition.Parse(ignitionBytes)
+	fields["parse_ms"] = milliseconds(time.Since(start))
+	return result
+}
+
+// A transpiled snippet, normalized to check it for conflicts.
+type transpiledSnippet struct {
+	transpiled
+	normalized types_v3_4.Config
+}
+
+// Transpile snippets concurrently, as translating snippets which embed large files takes
+// a while. The snippets are returned in input order, or diagnostics for every snippet
+// which failed. Snippets which are not started yet are skipped once ctx is done.
+func transpileSnippets(ctx context.Context, snippets []string, options translateOptions, maxVersion *semver.Version) ([]transpiledSnippet, Diagnostics) {
+	results := make([]transpiledSnippet, len(snippets))
+	errs := make([]error, len(snippets))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(runtime.GOMAXPROCS(0), len(snippets)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				inputCtx := tflog.SubsystemSetField(ctx, logSubsystemTranspile, "input", fmt.Sprintf("snippet %d", i))
+				results[i].transpiled = transpileButane(inputCtx, snippets[i], options, maxVersion)
+				if errs[i] = results[i].err; errs[i] != nil {
+					continue
+				}
+				results[i].normalized, errs[i] = configutil.Normalize(results[i].config)
+			}
+		}()
+	}
+schedule:
+	for i := range snippets {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errorDiagnostics(err)
+	}
+	var diags Diagnostics
+	for i, err := range errs {
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityError,
+				Summary:  fmt.Sprintf("snippet parse error: %v", err),
+				Detail:   fmt.Sprintf("snippet %d", i),
+			})
+		}
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+	return results, nil
+}
+
+// prepare function to validate snippets against
+func ensureMaxVersion(maxVersion semver.Version) getConfigVersion {
+	return func(ignitionBytes []byte) (semver.Version, error) {
+		version, _, err := ignition_util.GetConfigVersion(ignitionBytes)
+		if err != nil {
+			return semver.Version{}, err
+		}
+		if maxVersion.LessThan(version) {
+			return semver.Version{}, fmt.Errorf(
+				"version %s is newer than max version %s and therefore incompatible",
+				version.String(),
+				maxVersion.String(),
+			)
+		}
+		return maxVersion, nil
+	}
+}
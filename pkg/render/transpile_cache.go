@@ -0,0 +1,142 @@
+package render
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/coreos/vcontext/report"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// Number of transpiled inputs kept in memory. Inputs are content and snippets
+// of single configs, so this is plenty for large fleets of a few roles.
+const transpileCacheSize = 512
+
+// Transpile cache shared by all renders of the process, e.g. all data sources of the provider.
+var providerTranspileCache = newTranspileCache(transpileCacheSize)
+
+// Identifies a transpiled input: its Butane config, the translate options and the
+// Ignition version it is parsed as, which is empty for the version of the input.
+type transpileKey struct {
+	digest   [sha256.Size]byte
+	strict   bool
+	filesDir string
+	target   string
+}
+
+func newTranspileKey(butaneConfig string, options translateOptions, target string) transpileKey {
+	return transpileKey{
+		digest:   sha256.Sum256([]byte(butaneConfig)),
+		strict:   options.strict,
+		filesDir: options.filesDir,
+		target:   target,
+	}
+}
+
+type localFilesCacheKey struct{}
+
+// Returns a context in which renders share a transpile cache for inputs embedding local
+// files, e.g. the renders of all nodes of a config set, so inputs shared by the renders
+// are transpiled once. Local files are read once per context, changes made to them
+// while it is used are not picked up.
+func WithLocalFilesCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localFilesCacheKey{}, newTranspileCache(transpileCacheSize))
+}
+
+// Returns the cache of WithLocalFilesCache, or nil.
+func localFilesCache(ctx context.Context) *transpileCache {
+	cache, _ := ctx.Value(localFilesCacheKey{}).(*transpileCache)
+	return cache
+}
+
+// Result of transpileButane. The config is shared by all users of the cache entry,
+// so it must not be modified; Ignition merges create new configs.
+type transpiled struct {
+	config   interface{}
+	version  semver.Version
+	ignition configutil.Library
+	report   report.Report
+	err      error
+}
+
+type transpileCacheEntry struct {
+	key   transpileKey
+	value transpiled
+	// closed once value is set
+	done chan struct{}
+}
+
+// Bounded least recently used cache of transpiled inputs, safe for concurrent use
+// by data sources read in parallel. Concurrent reads of an input which is not cached
+// yet wait for the first one to transpile it.
+type transpileCache struct {
+	mu        sync.Mutex
+	size      int
+	entries   map[transpileKey]*list.Element
+	lru       *list.List
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newTranspileCache(size int) *transpileCache {
+	return &transpileCache{
+		size:    size,
+		entries: map[transpileKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Returns the cached result for key, calling transpile to create it if needed.
+// Errors are cached as well, as transpiling the same input fails the same way.
+// Waiting for a concurrent transpilation of the input stops once ctx is done.
+func (c *transpileCache) get(ctx context.Context, key transpileKey, transpile func() transpiled) transpiled {
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		c.hits++
+		stats := c.stats(key)
+		c.mu.Unlock()
+		tflog.SubsystemDebug(ctx, logSubsystemTranspile, "transpile cache hit", stats)
+		entry := element.Value.(*transpileCacheEntry)
+		select {
+		case <-entry.done:
+			return entry.value
+		case <-ctx.Done():
+			return transpiled{err: ctx.Err()}
+		}
+	}
+	c.misses++
+	entry := &transpileCacheEntry{key: key, done: make(chan struct{})}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		delete(c.entries, c.lru.Remove(oldest).(*transpileCacheEntry).key)
+		c.evictions++
+	}
+	stats := c.stats(key)
+	c.mu.Unlock()
+	tflog.SubsystemDebug(ctx, logSubsystemTranspile, "transpile cache miss", stats)
+
+	defer close(entry.done)
+	entry.value = transpile()
+	return entry.value
+}
+
+// Must be called with mu held.
+func (c *transpileCache) stats(key transpileKey) map[string]interface{} {
+	return map[string]interface{}{
+		"cache_key":       fmt.Sprintf("%x", key.digest[:8]),
+		"cache_hits":      c.hits,
+		"cache_misses":    c.misses,
+		"cache_evictions": c.evictions,
+		"cache_entries":   c.lru.Len(),
+		"cache_size":      c.size,
+	}
+}
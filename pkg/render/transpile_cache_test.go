@@ -0,0 +1,119 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTranspileCache(t *testing.T) {
+	cache := newTranspileCache(2)
+	calls := 0
+	get := func(content string, strict bool, target string) error {
+		return cache.get(context.Background(), newTranspileKey(content, translateOptions{strict: strict}, target), func() transpiled {
+			calls++
+			return transpiled{err: fmt.Errorf("%s %t %s", content, strict, target)}
+		}).err
+	}
+
+	if err := get("a", false, ""); err.Error() != "a false " {
+		t.Errorf("unexpected result %v", err)
+	}
+	// strict and target version are part of the key
+	get("a", true, "")
+	get("a", true, "3.4.0")
+	if calls != 3 {
+		t.Errorf("expected 3 transpilations, got %d", calls)
+	}
+	if err := get("a", true, "3.4.0"); err.Error() != "a true 3.4.0" || calls != 3 {
+		t.Errorf("expected a cached result, got %v after %d transpilations", err, calls)
+	}
+	// the least recently used entry was evicted
+	get("a", false, "")
+	if calls != 4 {
+		t.Errorf("expected an evicted entry to be transpiled again, got %d transpilations", calls)
+	}
+	if cache.hits != 1 || cache.misses != 4 || cache.evictions != 2 || cache.lru.Len() != 2 {
+		t.Errorf("unexpected stats: %d hits, %d misses, %d evictions, %d entries", cache.hits, cache.misses, cache.evictions, cache.lru.Len())
+	}
+}
+
+func TestTranspileCacheConcurrent(t *testing.T) {
+	cache := newTranspileCache(transpileCacheSize)
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := fmt.Sprintf("snippet %d", i%5)
+			result := cache.get(context.Background(), newTranspileKey(content, translateOptions{}, ""), func() transpiled {
+				atomic.AddInt32(&calls, 1)
+				return transpiled{err: fmt.Errorf("%s", content)}
+			})
+			if result.err == nil || result.err.Error() != content {
+				t.Errorf("expected the result of %q, got %v", content, result.err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if calls != 5 {
+		t.Errorf("expected each input to be transpiled once, got %d transpilations", calls)
+	}
+}
+
+func TestTranspileButaneCached(t *testing.T) {
+	content := "variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/cached\n"
+	key := newTranspileKey(content, translateOptions{}, "")
+	hits := providerTranspileCache.hits
+	for i := 0; i < 2; i++ {
+		result := transpileButane(context.Background(), content, translateOptions{}, nil)
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+		if result.version.String() != "3.4.0" {
+			t.Errorf("expected version 3.4.0, got %s", result.version)
+		}
+	}
+	providerTranspileCache.mu.Lock()
+	defer providerTranspileCache.mu.Unlock()
+	if _, ok := providerTranspileCache.entries[key]; !ok || providerTranspileCache.hits <= hits {
+		t.Errorf("expected the second transpilation to hit the cache")
+	}
+}
+
+func TestTranspileButaneLocalFilesCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "motd"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content := "variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/motd\n      contents:\n        local: motd\n"
+	options := translateOptions{filesDir: dir}
+	key := newTranspileKey(content, options, "")
+
+	transpileButane(context.Background(), content, options, nil)
+	providerTranspileCache.mu.Lock()
+	_, cached := providerTranspileCache.entries[key]
+	providerTranspileCache.mu.Unlock()
+	if cached {
+		t.Errorf("expected inputs with local files not to be cached by the provider")
+	}
+
+	ctx := WithLocalFilesCache(context.Background())
+	for i := 0; i < 2; i++ {
+		if result := transpileButane(ctx, content, options, nil); result.err != nil {
+			t.Fatal(result.err)
+		}
+	}
+	cache := localFilesCache(ctx)
+	if _, ok := cache.entries[key]; !ok || cache.misses != 1 || cache.hits != 1 {
+		t.Errorf("expected the input to be transpiled once, got %d misses and %d hits", cache.misses, cache.hits)
+	}
+	if _, ok := cache.entries[newTranspileKey(content, translateOptions{filesDir: t.TempDir()}, "")]; ok {
+		t.Errorf("expected other files dirs not to share the entry")
+	}
+}
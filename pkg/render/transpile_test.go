@@ -0,0 +1,52 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-semver/semver"
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+)
+
+func TestTranspileSnippets(t *testing.T) {
+	version := *semver.New("3.4.0")
+	var snippets []string
+	for i := 0; i < 20; i++ {
+		snippets = append(snippets, fmt.Sprintf("variant: fcos\nversion: 1.5.0\nstorage:\n  files:\n    - path: /etc/snippet-%d\n", i))
+	}
+
+	results, diags := transpileSnippets(context.Background(), snippets, translateOptions{}, &version)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	for i, result := range results {
+		if path := result.config.(types_v3_4.Config).Storage.Files[0].Path; path != fmt.Sprintf("/etc/snippet-%d", i) {
+			t.Errorf("snippet %d: expected its own file, got %s", i, path)
+		}
+		if len(result.normalized.Storage.Files) != 1 {
+			t.Errorf("snippet %d: expected a normalized config", i)
+		}
+	}
+
+	// all failing snippets are reported
+	snippets[3] = "foo"
+	snippets[17] = "variant: fcos\nversion: 9.9.9\n"
+	_, diags = transpileSnippets(context.Background(), snippets, translateOptions{}, &version)
+	if len(diags) != 2 || diags[0].Detail != "snippet 3" || diags[1].Detail != "snippet 17" {
+		t.Errorf("expected errors for snippets 3 and 17, got %v", diags)
+	}
+	for _, d := range diags {
+		if !strings.HasPrefix(d.Summary, "snippet parse error: ") {
+			t.Errorf("unexpected summary %q", d.Summary)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, diags = transpileSnippets(ctx, snippets, translateOptions{}, &version)
+	if len(diags) != 1 || diags[0].Summary != context.Canceled.Error() {
+		t.Errorf("expected a cancellation error, got %v", diags)
+	}
+}
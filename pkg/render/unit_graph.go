@@ -0,0 +1,423 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// A dependency between two units. Install dependencies (WantedBy, RequiredBy, UpheldBy)
+// point from the unit to its target, triggers from a timer, path or socket to its unit.
+type unitEdge struct {
+	from string
+	to   string
+	kind string
+}
+
+// The dependency graph of the units of a merged config.
+type unitGraph struct {
+	variant string
+	// units defined in the config, with the unit files of their contents and dropins
+	units map[string][]unitFile
+	// unit files written to storage, e.g. /etc/systemd/system/foo.service
+	unitFiles map[string]bool
+	enabled   map[string]bool
+	edges     []unitEdge
+}
+
+var requirementKinds = []string{"Wants", "Requires", "Requisite", "BindsTo", "PartOf", "Upholds", "OnFailure", "OnSuccess"}
+
+var installKinds = []string{"WantedBy", "RequiredBy", "UpheldBy"}
+
+var unitFileDirectories = []string{"/etc/systemd/system", "/usr/lib/systemd/system", "/usr/local/lib/systemd/system"}
+
+// Variant of a Butane config, e.g. "fcos" or "flatcar".
+func butaneVariant(butaneConfig string) string {
+	var header struct {
+		Variant string `yaml:"variant"`
+	}
+	if err := yaml.Unmarshal([]byte(butaneConfig), &header); err != nil {
+		return ""
+	}
+	return header.Variant
+}
+
+func newUnitGraph(config types_v3_4.Config, variant string) *unitGraph {
+	graph := &unitGraph{
+		variant:   variant,
+		units:     map[string][]unitFile{},
+		unitFiles: map[string]bool{},
+		enabled:   map[string]bool{},
+	}
+	for _, file := range config.Storage.Files {
+		for _, directory := range unitFileDirectories {
+			if path.Dir(file.Path) == directory {
+				graph.unitFiles[path.Base(file.Path)] = true
+			}
+		}
+	}
+	for _, unit := range config.Systemd.Units {
+		var files []unitFile
+		if unit.Contents != nil {
+			file, _ := parseUnit(*unit.Contents)
+			files = append(files, file)
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents != nil {
+				file, _ := parseUnit(*dropin.Contents)
+				files = append(files, file)
+			}
+		}
+		graph.units[unit.Name] = files
+		graph.enabled[unit.Name] = configutil.BoolValue(unit.Enabled)
+		graph.addEdges(unit.Name, files)
+	}
+	return graph
+}
+
+func (g *unitGraph) addEdges(name string, files []unitFile) {
+	add := func(section, kind string) {
+		for _, value := range directiveValues(files, section, kind) {
+			for _, to := range strings.Fields(value) {
+				g.edges = append(g.edges, unitEdge{name, to, kind})
+			}
+		}
+	}
+	for _, kind := range requirementKinds {
+		add("Unit", kind)
+	}
+	add("Unit", "After")
+	add("Unit", "Before")
+	add("Unit", "Conflicts")
+	for _, kind := range installKinds {
+		add("Install", kind)
+	}
+
+	// timers, paths and sockets trigger the unit of the same name unless configured otherwise
+	base := strings.TrimSuffix(name, path.Ext(name))
+	switch unitType(name) {
+	case "timer":
+		g.edges = append(g.edges, unitEdge{name, firstNonEmpty(directiveValue(files, "Timer", "Unit"), base+".service"), "Triggers"})
+	case "path":
+		g.edges = append(g.edges, unitEdge{name, firstNonEmpty(directiveValue(files, "Path", "Unit"), base+".service"), "Triggers"})
+	case "socket":
+		service := base + ".service"
+		if directiveValue(files, "Socket", "Accept") == "yes" || directiveValue(files, "Socket", "Accept") == "true" {
+			service = base + "@.service"
+		}
+		g.edges = append(g.edges, unitEdge{name, firstNonEmpty(directiveValue(files, "Socket", "Service"), service), "Triggers"})
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Returns true if a unit is shipped by the OS of the graph's variant.
+func (g *unitGraph) shipped(name string) bool {
+	catalog := fcosUnits
+	if g.variant == "flatcar" {
+		catalog = flatcarUnits
+	}
+	return commonUnits[name] || catalog[name]
+}
+
+// Returns true if a unit exists on a machine provisioned with the config.
+func (g *unitGraph) known(name string) bool {
+	switch unitType(name) {
+	case "device", "scope", "slice", "swap":
+		// generated by systemd on demand
+		return true
+	}
+	candidates := []string{name}
+	if prefix, instance, ok := strings.Cut(name, "@"); ok && !strings.HasPrefix(instance, ".") {
+		candidates = append(candidates, prefix+"@"+path.Ext(name))
+	}
+	for _, candidate := range candidates {
+		if len(g.units[candidate]) > 0 || g.unitFiles[candidate] || g.shipped(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Problems of the graph: dependencies on unknown units, ordering cycles and
+// enabled units which nothing pulls in. Problems are reported with the input
+// which defined the unit, if known.
+func (g *unitGraph) problems(inputs *conflictTracker) []string {
+	var problems []string
+	report := func(unit, format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		if input := inputs.input("unit " + unit); input != "" {
+			problems = append(problems, fmt.Sprintf("unit %s (%s): %s", unit, input, message))
+		} else {
+			problems = append(problems, fmt.Sprintf("unit %s: %s", unit, message))
+		}
+	}
+
+	for _, name := range configutil.SortedKeys(g.units) {
+		if !g.known(name) {
+			report(name, "has no contents, and is neither written to storage nor shipped with %s", g.variantName())
+		}
+	}
+	for _, edge := range g.edges {
+		if edge.kind == "After" || edge.kind == "Before" || edge.kind == "Conflicts" {
+			continue
+		}
+		if !g.known(edge.to) {
+			report(edge.from, "%s=%s, which is neither defined in the config nor shipped with %s", edge.kind, edge.to, g.variantName())
+		}
+	}
+
+	for _, cycle := range g.orderingCycles() {
+		problems = append(problems, fmt.Sprintf("ordering cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	pulled := g.pulledIn()
+	for _, name := range configutil.SortedKeys(g.units) {
+		if !g.enabled[name] || pulled[name] || g.shipped(name) || len(g.units[name]) == 0 {
+			continue
+		}
+		var targets []string
+		for _, edge := range g.edges {
+			if edge.from == name && isInstallKind(edge.kind) {
+				targets = append(targets, edge.to)
+			}
+		}
+		if len(targets) > 0 {
+			report(name, "enabled, but nothing pulls in %s", strings.Join(targets, ", "))
+		}
+	}
+	return problems
+}
+
+func (g *unitGraph) variantName() string {
+	if g.variant == "" {
+		return "the OS"
+	}
+	return g.variant
+}
+
+func isInstallKind(kind string) bool {
+	for _, installKind := range installKinds {
+		if kind == installKind {
+			return true
+		}
+	}
+	return false
+}
+
+// Units which are started at boot or on demand, assuming that units shipped with the OS are.
+func (g *unitGraph) pulledIn() map[string]bool {
+	pulled := map[string]bool{}
+	isPulled := func(name string) bool {
+		return pulled[name] || g.shipped(name)
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, edge := range g.edges {
+			from, to := edge.from, edge.to
+			switch {
+			case isInstallKind(edge.kind):
+				if !g.enabled[edge.from] {
+					continue
+				}
+				from, to = edge.to, edge.from
+			case !pullingKinds[edge.kind]:
+				continue
+			}
+			if isPulled(from) && !pulled[to] {
+				pulled[to] = true
+				changed = true
+			}
+		}
+	}
+	return pulled
+}
+
+// Dependencies which start the unit they point to.
+var pullingKinds = stringSet("Wants", "Requires", "BindsTo", "Upholds", "OnFailure", "OnSuccess", "Triggers")
+
+// Ordering dependencies as edges from a unit to the units it is started after.
+func (g *unitGraph) orderingEdges() map[string][]string {
+	after := map[string][]string{}
+	for _, edge := range g.edges {
+		switch edge.kind {
+		case "After":
+			after[edge.from] = append(after[edge.from], edge.to)
+		case "Before":
+			after[edge.to] = append(after[edge.to], edge.from)
+		}
+	}
+	return after
+}
+
+// Find ordering cycles, each reported once starting at its smallest unit name.
+func (g *unitGraph) orderingCycles() [][]string {
+	after := g.orderingEdges()
+	for _, targets := range after {
+		sort.Strings(targets)
+	}
+	var cycles [][]string
+	reported := map[string]bool{}
+	for _, start := range configutil.SortedKeys(after) {
+		if reported[start] {
+			continue
+		}
+		cycle := findCycle(after, start)
+		if cycle == nil {
+			continue
+		}
+		for _, unit := range cycle {
+			reported[unit] = true
+		}
+		cycles = append(cycles, append(cycle, cycle[0]))
+	}
+	return cycles
+}
+
+// Find a path from start back to start, using a depth first search.
+func findCycle(edges map[string][]string, start string) []string {
+	visited := map[string]bool{}
+	var path []string
+	var visit func(unit string) bool
+	visit = func(unit string) bool {
+		path = append(path, unit)
+		for _, next := range edges[unit] {
+			if next == start {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+// Render the graph in the DOT language. Units defined in the config are drawn
+// as boxes, units shipped with the OS dashed and unknown units red.
+func (g *unitGraph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph units {\n")
+	nodes := map[string]bool{}
+	for name := range g.units {
+		nodes[name] = true
+	}
+	for _, edge := range g.edges {
+		nodes[edge.from] = true
+		nodes[edge.to] = true
+	}
+	for _, name := range configutil.SortedKeys(nodes) {
+		var attributes []string
+		switch {
+		case len(g.units[name]) > 0:
+			attributes = append(attributes, "shape=box")
+		case g.known(name):
+			attributes = append(attributes, "style=dashed")
+		default:
+			attributes = append(attributes, "color=red")
+		}
+		if g.enabled[name] {
+			attributes = append(attributes, "penwidth=2")
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", name, strings.Join(attributes, ", "))
+	}
+	edges := append([]unitEdge{}, g.edges...)
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].from != edges[j].from {
+			return edges[i].from < edges[j].from
+		}
+		if edges[i].to != edges[j].to {
+			return edges[i].to < edges[j].to
+		}
+		return edges[i].kind < edges[j].kind
+	})
+	for _, edge := range edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.from, edge.to, edge.kind)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func stringSet(names ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Units shipped with systemd on both Fedora CoreOS and Flatcar Container Linux.
+var commonUnits = stringSet(
+	"-.mount", "-.slice", "system.slice", "user.slice", "machine.slice", "tmp.mount",
+	"basic.target", "sysinit.target", "multi-user.target", "graphical.target", "default.target",
+	"network.target", "network-online.target", "network-pre.target", "nss-lookup.target",
+	"nss-user-lookup.target", "remote-fs.target", "remote-fs-pre.target", "local-fs.target",
+	"local-fs-pre.target", "sockets.target", "timers.target", "paths.target", "slices.target",
+	"swap.target", "cryptsetup.target", "cryptsetup-pre.target", "time-sync.target",
+	"time-set.target", "getty.target", "getty-pre.target", "shutdown.target", "reboot.target",
+	"poweroff.target", "halt.target", "emergency.target", "rescue.target", "final.target",
+	"umount.target", "sleep.target", "suspend.target", "hibernate.target", "ctrl-alt-del.target",
+	"machines.target", "system-update.target", "first-boot-complete.target", "kexec.target",
+	"exit.target", "rpcbind.target", "veritysetup.target", "integritysetup.target",
+	"remote-cryptsetup.target", "emergency.service", "rescue.service",
+	"systemd-journald.service", "systemd-journald.socket", "systemd-udevd.service",
+	"systemd-logind.service", "systemd-resolved.service", "systemd-networkd.service",
+	"systemd-networkd.socket", "systemd-networkd-wait-online.service", "systemd-timesyncd.service",
+	"systemd-tmpfiles-setup.service", "systemd-tmpfiles-clean.service",
+	"systemd-tmpfiles-clean.timer", "systemd-sysctl.service", "systemd-modules-load.service",
+	"systemd-remount-fs.service", "systemd-fsck@.service", "systemd-fsck-root.service",
+	"systemd-hostnamed.service", "systemd-machined.service", "systemd-journal-flush.service",
+	"systemd-user-sessions.service", "systemd-update-utmp.service", "systemd-random-seed.service",
+	"systemd-sysusers.service", "systemd-oomd.service", "systemd-sysext.service",
+	"systemd-timedated.service", "systemd-localed.service", "systemd-growfs@.service",
+	"getty@.service", "serial-getty@.service", "console-getty.service", "user@.service",
+	"user-runtime-dir@.service", "dbus.service", "dbus.socket", "sshd.service", "sshd.socket",
+	"sshd@.service", "ldconfig.service", "docker.service", "docker.socket", "containerd.service",
+	"iscsid.service", "iscsid.socket", "iscsi.service", "multipathd.service", "multipathd.socket",
+	"nfs-client.target", "rpc-statd.service", "fstrim.service", "fstrim.timer",
+	"logrotate.service", "logrotate.timer", "polkit.service", "chronyd.service",
+	"afterburn.service", "afterburn-sshkeys@.service", "auditd.service",
+)
+
+// Units shipped with Fedora CoreOS.
+var fcosUnits = stringSet(
+	"NetworkManager.service", "NetworkManager-wait-online.service",
+	"NetworkManager-dispatcher.service", "zincati.service", "rpm-ostreed.service",
+	"rpm-ostreed-automatic.service", "rpm-ostreed-automatic.timer", "rpm-ostree-countme.service",
+	"rpm-ostree-countme.timer", "podman.service", "podman.socket", "podman-auto-update.service",
+	"podman-auto-update.timer", "podman-restart.service", "afterburn-checkin.service",
+	"afterburn-firstboot-checkin.service", "sssd.service", "kdump.service", "dbus-broker.service",
+	"raid-check.service", "raid-check.timer", "nftables.service", "bootupd.service",
+	"bootupd.socket", "coreos-printk-quiet.service", "coreos-check-ssh-keys.service",
+	"clevis-luks-askpass.path", "wpa_supplicant.service", "systemd-homed.service",
+)
+
+// Units shipped with Flatcar Container Linux.
+var flatcarUnits = stringSet(
+	"etcd-member.service", "flanneld.service", "locksmithd.service", "update-engine.service",
+	"ntpd.service", "coreos-metadata.service", "coreos-metadata-sshkeys@.service",
+	"oem-cloudinit.service", "user-cloudinit@.path", "user-cloudinit@.service",
+	"update-ca-certificates.service", "tcsd.service", "ensure-sysext.service",
+	"docker-flannel.service", "systemd-networkd-wait-online@.service",
+)
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestUnitGraphProblems(t *testing.T) {
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"systemd": {"units": [
+			{"name": "app.service", "enabled": true, "contents": "[Unit]\nRequires=db.service missing.service\nAfter=db.service\n[Install]\nWantedBy=multi-user.target\n"},
+			{"name": "db.service", "contents": "[Unit]\nAfter=app.service\n"},
+			{"name": "report.service", "enabled": true, "contents": "[Install]\nWantedBy=custom.target\n"},
+			{"name": "custom.target", "contents": "[Unit]\nDescription=Custom\n"},
+			{"name": "backup.timer", "enabled": true, "contents": "[Install]\nWantedBy=timers.target\n"},
+			{"name": "locksmithd.service", "enabled": true}
+		]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := newConflictTracker()
+	inputs.add("content", config)
+
+	expected := []string{
+		"unit locksmithd.service (content): has no contents, and is neither written to storage nor shipped with fcos",
+		"unit app.service (content): Requires=missing.service, which is neither defined in the config nor shipped with fcos",
+		"unit backup.timer (content): Triggers=backup.service, which is neither defined in the config nor shipped with fcos",
+		"ordering cycle: app.service -> db.service -> app.service",
+		"unit report.service (content): enabled, but nothing pulls in custom.target",
+	}
+	problems := newUnitGraph(config, "fcos").problems(inputs)
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %q", len(expected), len(problems), problems)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], problems[i])
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package render
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	types_v3_4 "github.com/coreos/ignition/v2/config/v3_4/types"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+// A parsed systemd unit file or dropin.
+type unitFile struct {
+	sections []unitSection
+}
+
+type unitSection struct {
+	name       string
+	line       int
+	directives []unitDirective
+}
+
+type unitDirective struct {
+	key   string
+	value string
+	line  int
+}
+
+// Parse the contents of a unit file or dropin. Lines which are no section header,
+// comment or assignment are returned as problems, parsing continues after them.
+func parseUnit(contents string) (unitFile, []string) {
+	var unit unitFile
+	var problems []string
+	lines := strings.Split(contents, "\n")
+	for i := 0; i < len(lines); i++ {
+		number := i + 1
+		line := strings.TrimSpace(lines[i])
+		// a trailing backslash continues the line, comments within are skipped
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			next := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(next, "#") || strings.HasPrefix(next, ";") {
+				continue
+			}
+			line = strings.TrimSuffix(line, "\\") + " " + next
+		}
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				problems = append(problems, fmt.Sprintf("line %d: invalid section header %s", number, line))
+				continue
+			}
+			unit.sections = append(unit.sections, unitSection{name: line[1 : len(line)-1], line: number})
+		case !strings.Contains(line, "="):
+			problems = append(problems, fmt.Sprintf("line %d: missing '=' in %q", number, line))
+		case len(unit.sections) == 0:
+			problems = append(problems, fmt.Sprintf("line %d: assignment outside of a section", number))
+		default:
+			key, value, _ := strings.Cut(line, "=")
+			section := &unit.sections[len(unit.sections)-1]
+			section.directives = append(section.directives, unitDirective{
+				key:   strings.TrimSpace(key),
+				value: strings.TrimSpace(value),
+				line:  number,
+			})
+		}
+	}
+	return unit, problems
+}
+
+// Values of a directive across a unit and its dropins, in order of precedence.
+// An empty assignment resets the values assigned before, like systemd does for list directives.
+func directiveValues(files []unitFile, section, key string) []string {
+	var values []string
+	for _, file := range files {
+		for _, s := range file.sections {
+			if s.name != section {
+				continue
+			}
+			for _, directive := range s.directives {
+				if directive.key != key {
+					continue
+				}
+				if directive.value == "" {
+					values = nil
+				} else {
+					values = append(values, directive.value)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// Last value of a directive across a unit and its dropins.
+func directiveValue(files []unitFile, section, key string) string {
+	values := directiveValues(files, section, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Type of a unit, the extension of its name, e.g. "service".
+func unitType(name string) string {
+	return strings.TrimPrefix(path.Ext(name), ".")
+}
+
+// Lint unit and dropin contents of a config. Problems are reported per unit,
+// together with the input which defined the unit or dropin, if known.
+func lintUnits(config types_v3_4.Config, inputs *conflictTracker) []string {
+	var problems []string
+	for _, unit := range config.Systemd.Units {
+		report := func(input, format string, args ...interface{}) {
+			message := fmt.Sprintf(format, args...)
+			if input != "" {
+				problems = append(problems, fmt.Sprintf("unit %s (%s): %s", unit.Name, input, message))
+			} else {
+				problems = append(problems, fmt.Sprintf("unit %s: %s", unit.Name, message))
+			}
+		}
+		unitInput := inputs.input("unit " + unit.Name)
+
+		var files []unitFile
+		if unit.Contents != nil {
+			file, syntaxProblems := parseUnit(*unit.Contents)
+			for _, problem := range syntaxProblems {
+				report(unitInput, "%s", problem)
+			}
+			for _, problem := range lintDirectives(unit.Name, file) {
+				report(unitInput, "%s", problem)
+			}
+			files = append(files, file)
+		}
+		for _, dropin := range unit.Dropins {
+			if dropin.Contents == nil {
+				continue
+			}
+			dropinInput := inputs.input(fmt.Sprintf("unit %s dropin %s", unit.Name, dropin.Name))
+			file, syntaxProblems := parseUnit(*dropin.Contents)
+			for _, problem := range syntaxProblems {
+				report(dropinInput, "dropin %s: %s", dropin.Name, problem)
+			}
+			for _, problem := range lintDirectives(unit.Name, file) {
+				report(dropinInput, "dropin %s: %s", dropin.Name, problem)
+			}
+			files = append(files, file)
+		}
+
+		// without contents, the unit is shipped by the OS and only partially known
+		if unit.Contents == nil {
+			continue
+		}
+		if unitType(unit.Name) == "service" {
+			serviceType := directiveValue(files, "Service", "Type")
+			if serviceType != "oneshot" && len(directiveValues(files, "Service", "ExecStart")) == 0 {
+				report(unitInput, "no ExecStart= in [Service], only services of Type=oneshot may omit it")
+			}
+		}
+		if configutil.BoolValue(unit.Enabled) && !strings.Contains(unit.Name, "@.") {
+			installed := false
+			for _, key := range []string{"WantedBy", "RequiredBy", "UpheldBy", "Alias", "Also"} {
+				installed = installed || len(directiveValues(files, "Install", key)) > 0
+			}
+			if !installed {
+				report(unitInput, "enabled, but [Install] has no WantedBy= or RequiredBy=")
+			}
+		}
+	}
+	return problems
+}
+
+// Check sections and directives of a unit file against the known directives of its unit type.
+// Sections and directives prefixed with X- are ignored by systemd and accepted.
+func lintDirectives(name string, file unitFile) []string {
+	var problems []string
+	sections := unitSections(unitType(name))
+	for _, section := range file.sections {
+		if strings.HasPrefix(section.name, "X-") {
+			continue
+		}
+		known, ok := sections[section.name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: unknown section [%s] for %s units", section.line, section.name, unitType(name)))
+			continue
+		}
+		for _, directive := range section.directives {
+			if strings.HasPrefix(directive.key, "X-") || known[directive.key] {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("line %d: unknown directive %s= in [%s]", directive.line, directive.key, section.name))
+		}
+	}
+	return problems
+}
+
+// Sections and their directives allowed in units of a type.
+func unitSections(unitType string) map[string]map[string]bool {
+	sections := map[string]map[string]bool{
+		"Unit":    unitDirectives,
+		"Install": installDirectives,
+	}
+	switch unitType {
+	case "service":
+		sections["Service"] = directiveSet(serviceDirectives, execDirectives, killDirectives, resourceControlDirectives)
+	case "socket":
+		sections["Socket"] = directiveSet(socketDirectives, execDirectives, killDirectives, resourceControlDirectives)
+	case "mount":
+		sections["Mount"] = directiveSet(mountDirectives, execDirectives, killDirectives, resourceControlDirectives)
+	case "swap":
+		sections["Swap"] = directiveSet(swapDirectives, execDirectives, killDirectives, resourceControlDirectives)
+	case "automount":
+		sections["Automount"] = directiveSet(automountDirectives)
+	case "timer":
+		sections["Timer"] = directiveSet(timerDirectives)
+	case "path":
+		sections["Path"] = directiveSet(pathDirectives)
+	case "slice":
+		sections["Slice"] = directiveSet(resourceControlDirectives)
+	case "scope":
+		sections["Scope"] = directiveSet(scopeDirectives, killDirectives, resourceControlDirectives)
+	}
+	return sections
+}
+
+func directiveSet(lists ...[]string) map[string]bool {
+	set := map[string]bool{}
+	for _, list := range lists {
+		for _, directive := range list {
+			set[directive] = true
+		}
+	}
+	return set
+}
+
+var conditions = []string{
+	"Architecture", "Firmware", "Virtualization", "Host", "KernelCommandLine", "KernelVersion",
+	"Credential", "Environment", "Security", "Capability", "ACPower", "NeedsUpdate", "FirstBoot",
+	"PathExists", "PathExistsGlob", "PathIsDirectory", "PathIsSymbolicLink", "PathIsMountPoint",
+	"PathIsReadWrite", "PathIsEncrypted", "DirectoryNotEmpty", "FileNotEmpty", "FileIsExecutable",
+	"User", "Group", "ControlGroupController", "Memory", "CPUs", "CPUFeature", "OSRelease",
+	"MemoryPressure", "CPUPressure", "IOPressure",
+}
+
+var unitDirectives = func() map[string]bool {
+	set := directiveSet([]string{
+		"Description", "Documentation", "Wants", "Requires", "Requisite", "BindsTo", "PartOf",
+		"Upholds", "Conflicts", "Before", "After", "OnFailure", "OnSuccess", "PropagatesReloadTo",
+		"ReloadPropagatedFrom", "PropagatesStopTo", "StopPropagatedFrom", "JoinsNamespaceOf",
+		"RequiresMountsFor", "WantsMountsFor", "OnFailureJobMode", "OnSuccessJobMode",
+		"OnFailureIsolate", "IgnoreOnIsolate", "StopWhenUnneeded", "RefuseManualStart",
+		"RefuseManualStop", "AllowIsolate", "DefaultDependencies", "SurviveFinalKillSignal",
+		"CollectMode", "FailureAction", "SuccessAction", "FailureActionExitStatus",
+		"SuccessActionExitStatus", "JobTimeoutSec", "JobRunningTimeoutSec", "JobTimeoutAction",
+		"JobTimeoutRebootArgument", "StartLimitIntervalSec", "StartLimitInterval", "StartLimitBurst",
+		"StartLimitAction", "RebootArgument", "SourcePath",
+	})
+	for _, condition := range conditions {
+		set["Condition"+condition] = true
+		set["Assert"+condition] = true
+	}
+	return set
+}()
+
+var installDirectives = directiveSet([]string{
+	"Alias", "WantedBy", "RequiredBy", "UpheldBy", "Also", "DefaultInstance",
+})
+
+var serviceDirectives = []string{
+	"Type", "ExitType", "RemainAfterExit", "GuessMainPID", "PIDFile", "BusName", "ExecStart",
+	"ExecStartPre", "ExecStartPost", "ExecCondition", "ExecReload", "ExecStop", "ExecStopPost",
+	"RestartSec", "RestartSteps", "RestartMaxDelaySec", "TimeoutStartSec", "TimeoutStopSec",
+	"TimeoutAbortSec", "TimeoutSec", "TimeoutStartFailureMode", "TimeoutStopFailureMode",
+	"RuntimeMaxSec", "RuntimeRandomizedExtraSec", "WatchdogSec", "Restart", "RestartMode",
+	"SuccessExitStatus", "RestartPreventExitStatus", "RestartForceExitStatus",
+	"RootDirectoryStartOnly", "NonBlocking", "NotifyAccess", "Sockets", "FileDescriptorStoreMax",
+	"FileDescriptorStorePreserve", "USBFunctionDescriptors", "USBFunctionStrings", "OOMPolicy",
+	"OpenFile", "ReloadSignal", "PermissionsStartOnly", "StartLimitInterval", "StartLimitIntervalSec",
+	"StartLimitBurst", "StartLimitAction", "FailureAction", "SuccessAction", "RebootArgument",
+}
+
+var execDirectives = []string{
+	"WorkingDirectory", "RootDirectory", "RootImage", "RootImageOptions", "RootEphemeral",
+	"RootHash", "RootHashSignature", "RootVerity", "RootImagePolicy", "MountImagePolicy",
+	"ExtensionImagePolicy", "MountAPIVFS", "ProtectProc", "ProcSubset", "BindPaths",
+	"BindReadOnlyPaths", "MountImages", "ExtensionImages", "ExtensionDirectories", "User", "Group",
+	"DynamicUser", "SupplementaryGroups", "SetLoginEnvironment", "PAMName", "CapabilityBoundingSet",
+	"AmbientCapabilities", "NoNewPrivileges", "SecureBits", "SELinuxContext", "AppArmorProfile",
+	"SmackProcessLabel", "LimitCPU", "LimitFSIZE", "LimitDATA", "LimitSTACK", "LimitCORE",
+	"LimitRSS", "LimitNOFILE", "LimitAS", "LimitNPROC", "LimitMEMLOCK", "LimitLOCKS",
+	"LimitSIGPENDING", "LimitMSGQUEUE", "LimitNICE", "LimitRTPRIO", "LimitRTTIME", "UMask",
+	"CoredumpFilter", "KeyringMode", "OOMScoreAdjust", "TimerSlackNSec", "Personality",
+	"IgnoreSIGPIPE", "Nice", "CPUSchedulingPolicy", "CPUSchedulingPriority",
+	"CPUSchedulingResetOnFork", "CPUAffinity", "NUMAPolicy", "NUMAMask", "IOSchedulingClass",
+	"IOSchedulingPriority", "ProtectSystem", "ProtectHome", "RuntimeDirectory", "StateDirectory",
+	"CacheDirectory", "LogsDirectory", "ConfigurationDirectory", "RuntimeDirectoryMode",
+	"StateDirectoryMode", "CacheDirectoryMode", "LogsDirectoryMode", "ConfigurationDirectoryMode",
+	"RuntimeDirectoryPreserve", "TimeoutCleanSec", "ReadWritePaths", "ReadOnlyPaths",
+	"InaccessiblePaths", "ExecPaths", "NoExecPaths", "ReadWriteDirectories", "ReadOnlyDirectories",
+	"InaccessibleDirectories", "TemporaryFileSystem", "PrivateTmp", "PrivateDevices",
+	"PrivateNetwork", "NetworkNamespacePath", "PrivateIPC", "IPCNamespacePath", "MemoryKSM",
+	"PrivateUsers", "ProtectHostname", "ProtectClock", "ProtectKernelTunables",
+	"ProtectKernelModules", "ProtectKernelLogs", "ProtectControlGroups", "RestrictAddressFamilies",
+	"RestrictFileSystems", "RestrictNamespaces", "LockPersonality", "MemoryDenyWriteExecute",
+	"RestrictRealtime", "RestrictSUIDSGID", "RemoveIPC", "PrivateMounts", "MountFlags",
+	"SystemCallFilter", "SystemCallErrorNumber", "SystemCallArchitectures", "SystemCallLog",
+	"Environment", "EnvironmentFile", "PassEnvironment", "UnsetEnvironment", "StandardInput",
+	"StandardOutput", "StandardError", "StandardInputText", "StandardInputData", "LogLevelMax",
+	"LogExtraFields", "LogRateLimitIntervalSec", "LogRateLimitBurst", "LogFilterPatterns",
+	"LogNamespace", "SyslogIdentifier", "SyslogFacility", "SyslogLevel", "SyslogLevelPrefix",
+	"TTYPath", "TTYReset", "TTYVHangup", "TTYRows", "TTYColumns", "TTYVTDisallocate",
+	"LoadCredential", "LoadCredentialEncrypted", "ImportCredential", "SetCredential",
+	"SetCredentialEncrypted", "UtmpIdentifier", "UtmpMode",
+}
+
+var killDirectives = []string{
+	"KillMode", "KillSignal", "RestartKillSignal", "SendSIGHUP", "SendSIGKILL", "FinalKillSignal",
+	"WatchdogSignal",
+}
+
+var resourceControlDirectives = []string{
+	"CPUAccounting", "CPUWeight", "StartupCPUWeight", "CPUQuota", "CPUQuotaPeriodSec",
+	"AllowedCPUs", "StartupAllowedCPUs", "AllowedMemoryNodes", "StartupAllowedMemoryNodes",
+	"MemoryAccounting", "MemoryMin", "MemoryLow", "StartupMemoryLow", "DefaultStartupMemoryLow",
+	"MemoryHigh", "StartupMemoryHigh", "MemoryMax", "StartupMemoryMax", "MemorySwapMax",
+	"StartupMemorySwapMax", "MemoryZSwapMax", "StartupMemoryZSwapMax", "MemoryZSwapWriteback",
+	"TasksAccounting", "TasksMax", "IOAccounting", "IOWeight", "StartupIOWeight", "IODeviceWeight",
+	"IOReadBandwidthMax", "IOWriteBandwidthMax", "IOReadIOPSMax", "IOWriteIOPSMax",
+	"IODeviceLatencyTargetSec", "IPAccounting", "IPAddressAllow", "IPAddressDeny",
+	"SocketBindAllow", "SocketBindDeny", "RestrictNetworkInterfaces", "NFTSet",
+	"IPIngressFilterPath", "IPEgressFilterPath", "BPFProgram", "DeviceAllow", "DevicePolicy",
+	"Slice", "Delegate", "DelegateSubgroup", "DisableControllers", "ManagedOOMSwap",
+	"ManagedOOMMemoryPressure", "ManagedOOMMemoryPressureLimit", "ManagedOOMPreference",
+	"MemoryPressureWatch", "MemoryPressureThresholdSec", "CoredumpReceive", "CPUShares",
+	"StartupCPUShares", "MemoryLimit", "BlockIOAccounting", "BlockIOWeight",
+	"StartupBlockIOWeight", "BlockIODeviceWeight", "BlockIOReadBandwidth", "BlockIOWriteBandwidth",
+}
+
+var socketDirectives = []string{
+	"ListenStream", "ListenDatagram", "ListenSequentialPacket", "ListenFIFO", "ListenSpecial",
+	"ListenNetlink", "ListenMessageQueue", "ListenUSBFunction", "SocketProtocol", "BindIPv6Only",
+	"Backlog", "BindToDevice", "SocketUser", "SocketGroup", "SocketMode", "DirectoryMode", "Accept",
+	"Writable", "FlushPending", "MaxConnections", "MaxConnectionsPerSource", "KeepAlive",
+	"KeepAliveTimeSec", "KeepAliveIntervalSec", "KeepAliveProbes", "NoDelay", "Priority",
+	"DeferAcceptSec", "ReceiveBuffer", "SendBuffer", "IPTOS", "IPTTL", "Mark", "ReusePort",
+	"SmackLabel", "SmackLabelIPIn", "SmackLabelIPOut", "SELinuxContextFromNet", "PipeSize",
+	"MessageQueueMaxMessages", "MessageQueueMessageSize", "FreeBind", "Transparent", "Broadcast",
+	"PassCredentials", "PassSecurity", "PassPacketInfo", "Timestamping", "TCPCongestion",
+	"ExecStartPre", "ExecStartPost", "ExecStopPre", "ExecStopPost", "TimeoutSec", "Service",
+	"RemoveOnStop", "Symlinks", "FileDescriptorName", "TriggerLimitIntervalSec",
+	"TriggerLimitBurst", "PollLimitIntervalSec", "PollLimitBurst", "PassFileDescriptorsToExec",
+}
+
+var mountDirectives = []string{
+	"What", "Where", "Type", "Options", "SloppyOptions", "LazyUnmount", "ReadWriteOnly",
+	"ForceUnmount", "DirectoryMode", "TimeoutSec",
+}
+
+var automountDirectives = []string{
+	"Where", "ExtraOptions", "DirectoryMode", "TimeoutIdleSec",
+}
+
+var swapDirectives = []string{
+	"What", "Priority", "Options", "TimeoutSec",
+}
+
+var timerDirectives = []string{
+	"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec",
+	"OnCalendar", "AccuracySec", "RandomizedDelaySec", "FixedRandomDelay", "OnClockChange",
+	"OnTimezoneChange", "Unit", "Persistent", "WakeSystem", "RemainAfterElapse",
+}
+
+var pathDirectives = []string{
+	"PathExists", "PathExistsGlob", "PathChanged", "PathModified", "DirectoryNotEmpty", "Unit",
+	"MakeDirectory", "DirectoryMode", "TriggerLimitIntervalSec", "TriggerLimitBurst",
+}
+
+var scopeDirectives = []string{
+	"RuntimeMaxSec", "RuntimeRandomizedExtraSec", "OOMPolicy",
+}
@@ -0,0 +1,42 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/e-breuninger/terraform-provider-ignition/internal/configutil"
+)
+
+func TestLintUnits(t *testing.T) {
+	inputs := newConflictTracker()
+	config, err := configutil.ParseRendered([]byte(`{
+		"ignition": {"version": "3.4.0"},
+		"systemd": {"units": [
+			{"name": "hello.service", "enabled": true, "contents": "[Unit]\nDescription=Hello\n[Servcie]\nExecStart=/bin/true\n"},
+			{"name": "backup.service", "contents": "[Service]\nType=simple\nExecStartt=/bin/backup\n"},
+			{"name": "cleanup.service", "enabled": true, "contents": "[Service]\nType=oneshot\nExecStart=/bin/true \\\n  --verbose\n[Install]\nWantedBy=multi-user.target\n"},
+			{"name": "docker.service", "dropins": [{"name": "10-env.conf", "contents": "[Service]\nEnviroment=B=2\n"}]}
+		]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs.add("snippet 0", config)
+
+	expected := []string{
+		"unit hello.service (snippet 0): line 3: unknown section [Servcie] for service units",
+		"unit hello.service (snippet 0): no ExecStart= in [Service], only services of Type=oneshot may omit it",
+		"unit hello.service (snippet 0): enabled, but [Install] has no WantedBy= or RequiredBy=",
+		"unit backup.service (snippet 0): line 3: unknown directive ExecStartt= in [Service]",
+		"unit backup.service (snippet 0): no ExecStart= in [Service], only services of Type=oneshot may omit it",
+		"unit docker.service (snippet 0): dropin 10-env.conf: line 2: unknown directive Enviroment= in [Service]",
+	}
+	problems := lintUnits(config, inputs)
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %q", len(expected), len(problems), problems)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], problems[i])
+		}
+	}
+}